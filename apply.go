@@ -1,7 +1,10 @@
 package config
 
 import (
+	"encoding"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"reflect"
 	"strconv"
 	"strings"
@@ -10,59 +13,86 @@ import (
 	"github.com/aryszka/config/keys"
 )
 
-func tooManyValues(...interface{}) error {
-	return ErrTooManyValues
+func tooManyValues(count int) error {
+	return fmt.Errorf("%w: %d", ErrTooManyValues, count)
 }
 
-func invalidBooleanValue(...interface{}) error {
-	return ErrInvalidInputValue
+func invalidBooleanValue(reason interface{}) error {
+	return fmt.Errorf("%w: invalid boolean: %v", ErrInvalidInputValue, reason)
 }
 
-func invalidNumericValue(a ...interface{}) error {
-	if len(a) > 0 {
-		if err, _ := a[0].(error); errors.Is(err, ErrInvalidInputValue) {
-			return err
-		}
+func invalidNumericValue(reason interface{}) error {
+	if err, _ := reason.(error); errors.Is(err, ErrInvalidInputValue) {
+		return err
 	}
 
-	return ErrInvalidInputValue
+	return fmt.Errorf("%w: invalid number: %v", ErrInvalidInputValue, reason)
 }
 
-func overflow(...interface{}) error {
-	return ErrNumericOverflow
+func overflow(value interface{}) error {
+	return fmt.Errorf("%w: %v", ErrNumericOverflow, value)
 }
 
-func invalidStringValue(...interface{}) error {
-	return ErrInvalidInputValue
+func invalidStringValue(reason interface{}) error {
+	return fmt.Errorf("%w: invalid string: %v", ErrInvalidInputValue, reason)
 }
 
-func invalidStructureValue(...interface{}) error {
-	return ErrInvalidInputValue
+func invalidStructureValue(reason interface{}) error {
+	return fmt.Errorf("%w: invalid structure: %v", ErrInvalidInputValue, reason)
 }
 
-func invalidListValue(...interface{}) error {
-	return ErrInvalidInputValue
+func invalidListValue(reason interface{}) error {
+	return fmt.Errorf("%w: invalid list: %v", ErrInvalidInputValue, reason)
 }
 
-func invalidMapType(...interface{}) error {
-	return ErrInvalidTarget
+func invalidMapType(keyType reflect.Type) error {
+	return fmt.Errorf("%w: map key of type %v, expected string", ErrInvalidTarget, keyType)
 }
 
-func multipleCanonicalKeys(...interface{}) error {
-	return ErrConflictingKeys
+func multipleCanonicalKeys(raw []string) error {
+	return fmt.Errorf("%w: %s", ErrConflictingKeys, strings.Join(raw, ", "))
 }
 
-func invalidType(...interface{}) error {
-	return ErrInvalidInputValue
+func invalidType(t reflect.Type, reason interface{}) error {
+	return fmt.Errorf("%w: invalid value for %v: %v", ErrInvalidInputValue, t, reason)
 }
 
-func invalidTarget(...interface{}) error {
-	return ErrInvalidTarget
+func invalidTarget(t reflect.Type) error {
+	return fmt.Errorf("%w: %v", ErrInvalidTarget, t)
+}
+
+// the type flags of a node, e.g. list|structure, for the error messages
+func typeString(t NodeType) string {
+	names := []struct {
+		t    NodeType
+		name string
+	}{
+		{Nil, "null"},
+		{Bool, "bool"},
+		{Int, "int"},
+		{Float, "float"},
+		{String, "string"},
+		{List, "list"},
+		{Structure, "structure"},
+	}
+
+	var s []string
+	for _, n := range names {
+		if t&n.t != 0 {
+			s = append(s, n.name)
+		}
+	}
+
+	if len(s) == 0 {
+		return "undefined"
+	}
+
+	return strings.Join(s, "|")
 }
 
 func zeroOrOne(apply func(reflect.Value, Node) (bool, error), v reflect.Value, n Node) (bool, error) {
 	if n.Len() > 1 {
-		return false, tooManyValues()
+		return false, tooManyValues(n.Len())
 	}
 
 	if n.Len() == 0 {
@@ -129,7 +159,7 @@ func applyBool(v reflect.Value, n Node) (bool, error) {
 	t := n.Type()
 
 	if t&Bool == 0 {
-		return false, invalidBooleanValue()
+		return false, invalidBooleanValue(typeString(t))
 	}
 
 	if t&List != 0 {
@@ -157,7 +187,7 @@ func applyInt(v reflect.Value, n Node) (bool, error) {
 	t := n.Type()
 
 	if t&Int == 0 {
-		return false, invalidNumericValue()
+		return false, invalidNumericValue(typeString(t))
 	}
 
 	if t&List != 0 {
@@ -228,7 +258,7 @@ func applyUint(v reflect.Value, n Node) (bool, error) {
 	t := n.Type()
 
 	if t&Int == 0 {
-		return false, invalidNumericValue()
+		return false, invalidNumericValue(typeString(t))
 	}
 
 	if t&List != 0 {
@@ -307,7 +337,7 @@ func applyFloat(v reflect.Value, n Node) (bool, error) {
 	t := n.Type()
 
 	if t&Float == 0 {
-		return false, invalidNumericValue()
+		return false, invalidNumericValue(typeString(t))
 	}
 
 	value := n.Primitive()
@@ -358,7 +388,7 @@ func applyString(v reflect.Value, n Node) (bool, error) {
 	t := n.Type()
 
 	if t&String == 0 {
-		return false, invalidStringValue()
+		return false, invalidStringValue(typeString(t))
 	}
 
 	if t&List != 0 {
@@ -379,52 +409,190 @@ func exported(name string) bool {
 	return unicode.IsUpper([]rune(name)[0])
 }
 
+// carries the apply options to the nested nodes, and the struct tag of a field to its list items
+type fieldNode struct {
+	Node
+	tag     reflect.StructTag
+	options options
+}
+
+func withOptions(n Node, o options) Node {
+	if len(o.tagNames) == 0 && !o.allErrors {
+		return n
+	}
+
+	return fieldNode{Node: n, options: o}
+}
+
+func withTag(n Node, tag reflect.StructTag) Node {
+	fn, ok := n.(fieldNode)
+	if !ok && tag == "" {
+		return n
+	}
+
+	if !ok {
+		fn.Node = n
+	}
+
+	fn.tag = tag
+	return fn
+}
+
+func tagOf(n Node) reflect.StructTag {
+	if fn, ok := n.(fieldNode); ok {
+		return fn.tag
+	}
+
+	return ""
+}
+
+func optionsOf(n Node) options {
+	if fn, ok := n.(fieldNode); ok {
+		return fn.options
+	}
+
+	return options{}
+}
+
+func (n fieldNode) Item(i int) Node {
+	return fieldNode{Node: n.Node.Item(i), tag: n.tag, options: n.options}
+}
+
+func (n fieldNode) Field(key string) Node {
+	return withOptions(n.Node.Field(key), n.options)
+}
+
+func (n fieldNode) origin() origin  { return originOf(n.Node) }
+func (n fieldNode) failure() error  { return failureOf(n.Node) }
+func (n fieldNode) isUnset() bool   { return unsetOf(n.Node) }
+func (n fieldNode) ambiguous() bool { return ambiguousOf(n.Node) }
+
+func (n fieldNode) withStrategy(s MergeStrategy) Node {
+	n.Node = withMergeStrategy(n.Node, s)
+	return n
+}
+
+func (n fieldNode) forKind(k reflect.Kind) Node {
+	n.Node = mergedFor(n.Node, k)
+	return n
+}
+
+func applyField(v reflect.Value, n Node, f reflect.StructField) (bool, error) {
+	s, ok, err := fieldMergeStrategy(f)
+	if err != nil {
+		return false, err
+	}
+
+	if ok {
+		n = withMergeStrategy(n, s)
+	}
+
+	return apply(v, withTag(n, f.Tag))
+}
+
 func applyStruct(v reflect.Value, n Node) (bool, error) {
 	t := n.Type()
 
 	if t&Structure == 0 {
-		return false, invalidStructureValue()
+		return false, invalidStructureValue(typeString(t))
 	}
 
-	canonicalKeys := make(map[string]string)
+	o := optionsOf(n)
+	var errs []error
+	var canonicals []string
+	spellings := make(map[string][]string)
 	for _, key := range n.Keys() {
 		canonical := keys.CanonicalSymbol(key)
-		if _, has := canonicalKeys[canonical]; has {
-			// TODO: this decision should be made in the source or the reader
-			return false, multipleCanonicalKeys(canonical)
+		if _, has := spellings[canonical]; !has {
+			canonicals = append(canonicals, canonical)
 		}
 
-		canonicalKeys[canonical] = key
+		spellings[canonical] = append(spellings[canonical], key)
 	}
 
-	var set bool
-	vt := v.Type()
-	for i := 0; i < vt.NumField(); i++ {
-		f := vt.Field(i)
-		if !exported(f.Name) {
+	canonicalKeys := make(map[string]string)
+	for _, canonical := range canonicals {
+		if len(spellings[canonical]) == 1 {
+			canonicalKeys[canonical] = spellings[canonical][0]
 			continue
 		}
 
-		key, ok := canonicalKeys[keys.CanonicalSymbol(f.Name)]
-		if !ok {
-			continue
+		// TODO: this decision should be made in the source or the reader
+		err := errorIn(canonical, errorAt(n, multipleCanonicalKeys(spellings[canonical])))
+		if !o.allErrors {
+			return false, err
 		}
 
-		if isSet, err := apply(v.FieldByName(f.Name), n.Field(key)); err != nil {
-			return set, err
-		} else if isSet {
+		errs = append(errs, err)
+	}
+
+	var set bool
+	for _, f := range structFields(v.Type(), o) {
+		// the name takes precedence over the aliases
+		key, ok := canonicalKeys[f.name]
+		for i := 0; !ok && i < len(f.aliases); i++ {
+			key, ok = canonicalKeys[f.aliases[i]]
+		}
+
+		// the unset keys are handled as missing, e.g. --no-foo without merging
+		if ok && unsetOf(n.Field(key)) {
+			ok = false
+		}
+
+		fv := v.FieldByIndex(f.field.Index)
+		var isSet bool
+		if ok {
+			var err error
+			if isSet, err = applyField(fv, n.Field(key), f.field); err != nil {
+				if !o.allErrors {
+					return set, errorIn(f.name, err)
+				}
+
+				errs = append(errs, errorIn(f.name, err))
+				continue
+			}
+		}
+
+		if isSet {
 			set = true
+			continue
+		}
+
+		if err := applyDefault(fv, f.field, o); err != nil {
+			if !o.allErrors {
+				return set, errorIn(f.name, err)
+			}
+
+			errs = append(errs, errorIn(f.name, err))
 		}
 	}
 
-	return set, nil
+	return set, joinErrors(errs)
+}
+
+// whether a value of the type can be applied from a node of the node type, e.g. a structure-only node cannot
+// be applied to a string
+func acceptsNode(t reflect.Type, nt NodeType) bool {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t.Kind() == reflect.Interface:
+		return true
+	case t.Kind() == reflect.Map, t.Kind() == reflect.Struct && !isCustomDecoded(t):
+		return nt&Structure != 0
+	default:
+		return nt&(Primitive|List) != 0
+	}
 }
 
 func applyMap(v reflect.Value, n Node) (bool, error) {
+	n = mergedFor(n, reflect.Map)
 	t := n.Type()
 
 	if v.Type().Key().Kind() != reflect.String {
-		return false, invalidMapType()
+		return false, invalidMapType(v.Type().Key())
 	}
 
 	if t == Nil {
@@ -433,7 +601,7 @@ func applyMap(v reflect.Value, n Node) (bool, error) {
 	}
 
 	if t&Structure == 0 {
-		return false, invalidStructureValue()
+		return false, invalidStructureValue(typeString(t))
 	}
 
 	keys := n.Keys()
@@ -445,19 +613,38 @@ func applyMap(v reflect.Value, n Node) (bool, error) {
 		v.Set(reflect.MakeMap(v.Type()))
 	}
 
+	var errs []error
+	allErrors := optionsOf(n).allErrors
 	for _, key := range keys {
+		fn := n.Field(key)
+		if unsetOf(fn) || ambiguousOf(fn) && !acceptsNode(v.Type().Elem(), fn.Type()) {
+			continue
+		}
+
 		pfv := reflect.New(v.Type().Elem())
-		if _, err := apply(pfv, n.Field(key)); err != nil {
-			return true, err
+		set, err := apply(pfv, fn)
+		if err != nil {
+			if !allErrors {
+				return true, errorIn(key, err)
+			}
+
+			errs = append(errs, errorIn(key, err))
+			continue
+		}
+
+		// the env keys can be the prefixes of the longer ones, without a value of their own
+		if !set && ambiguousOf(fn) {
+			continue
 		}
 
 		v.SetMapIndex(reflect.ValueOf(key), pfv.Elem())
 	}
 
-	return true, nil
+	return true, joinErrors(errs)
 }
 
 func applyList(v reflect.Value, n Node) (bool, error) {
+	n = mergedFor(n, reflect.Slice)
 	t := n.Type()
 
 	if t == Nil {
@@ -466,7 +653,7 @@ func applyList(v reflect.Value, n Node) (bool, error) {
 	}
 
 	if t&List == 0 {
-		return false, invalidListValue()
+		return false, invalidListValue(typeString(t))
 	}
 
 	l := n.Len()
@@ -475,16 +662,23 @@ func applyList(v reflect.Value, n Node) (bool, error) {
 		return false, nil
 	}
 
+	var errs []error
+	allErrors := optionsOf(n).allErrors
 	for i := 0; i < l; i++ {
 		piv := reflect.New(v.Type().Elem())
 		if _, err := apply(piv, n.Item(i)); err != nil {
-			return true, err
+			if !allErrors {
+				return true, errorIn(strconv.Itoa(i), err)
+			}
+
+			errs = append(errs, errorIn(strconv.Itoa(i), err))
+			continue
 		}
 
 		v.Index(i).Set(piv.Elem())
 	}
 
-	return true, nil
+	return true, joinErrors(errs)
 }
 
 func applyInterface(v reflect.Value, n Node) (bool, error) {
@@ -496,18 +690,18 @@ func applyInterface(v reflect.Value, n Node) (bool, error) {
 	}
 
 	switch {
-	case t&Primitive != 0 && (t&List == 0 || n.Len() <= 1):
+	case t&Primitive != 0 && (t&List == 0 || n.Len() == 1):
 		t := reflect.TypeOf(n.Primitive())
 		if !t.Implements(v.Type()) {
-			return false, invalidType()
+			return false, invalidType(v.Type(), t)
 		}
 
 		v.Set(reflect.ValueOf(n.Primitive()))
 		return true, nil
-	case t&List != 0:
+	case t&List != 0 && (t&Structure == 0 || n.Len() > 0):
 		t := reflect.TypeOf([]interface{}{})
 		if !t.Implements(v.Type()) {
-			return false, invalidType()
+			return false, invalidType(v.Type(), t)
 		}
 
 		pv := reflect.New(t)
@@ -521,7 +715,7 @@ func applyInterface(v reflect.Value, n Node) (bool, error) {
 		m := map[string]interface{}{}
 		t := reflect.TypeOf(m)
 		if !t.Implements(v.Type()) {
-			return false, invalidType()
+			return false, invalidType(v.Type(), t)
 		}
 
 		vv := reflect.ValueOf(m)
@@ -554,8 +748,135 @@ func applyPointer(v reflect.Value, n Node) (bool, error) {
 	return true, nil
 }
 
+// Unmarshaler can be implemented by the target types that decode the config themselves. The received node
+// is the merged node of all the sources.
+type Unmarshaler interface {
+	UnmarshalConfig(Node) error
+}
+
+var (
+	unmarshalerType     = reflect.TypeOf((*Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+)
+
+func implements(t, iface reflect.Type) bool {
+	return t.Kind() != reflect.Ptr && t.Kind() != reflect.Interface && reflect.PtrTo(t).Implements(iface)
+}
+
+func isUnmarshaler(t reflect.Type) bool     { return implements(t, unmarshalerType) }
+func isTextUnmarshaler(t reflect.Type) bool { return implements(t, textUnmarshalerType) }
+func isJSONUnmarshaler(t reflect.Type) bool { return implements(t, jsonUnmarshalerType) }
+
+// the types that decode the config themselves instead of being decoded based on their kind
+func isCustomDecoded(t reflect.Type) bool {
+	return isUnmarshaler(t) || isTextUnmarshaler(t) || isJSONUnmarshaler(t)
+}
+
+func primitiveText(value interface{}) string {
+	switch tvalue := value.(type) {
+	case string:
+		return tvalue
+	case float64:
+		return strconv.FormatFloat(tvalue, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(tvalue), 'f', -1, 32)
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func applyUnmarshaler(v reflect.Value, n Node) (bool, error) {
+	if err := v.Addr().Interface().(Unmarshaler).UnmarshalConfig(n); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func applyTextUnmarshaler(v reflect.Value, n Node) (bool, error) {
+	t := n.Type()
+
+	if t == Nil {
+		v.Set(reflect.Zero(v.Type()))
+		return true, nil
+	}
+
+	if t&Primitive == 0 {
+		return false, invalidType(v.Type(), typeString(t))
+	}
+
+	if t&List != 0 {
+		return zeroOrOne(applyTextUnmarshaler, v, n)
+	}
+
+	text := primitiveText(n.Primitive())
+	if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text)); err != nil {
+		return false, invalidType(v.Type(), err)
+	}
+
+	return true, nil
+}
+
+func applyJSONUnmarshaler(v reflect.Value, n Node) (bool, error) {
+	var i interface{}
+	if set, err := applyInterface(reflect.ValueOf(&i).Elem(), n); !set || err != nil {
+		return false, err
+	}
+
+	b, err := json.Marshal(i)
+	if err != nil {
+		return false, invalidType(v.Type(), err)
+	}
+
+	if err := v.Addr().Interface().(json.Unmarshaler).UnmarshalJSON(b); err != nil {
+		return false, invalidType(v.Type(), err)
+	}
+
+	return true, nil
+}
+
+// structured nodes are passed to the json.Unmarshaler, primitive ones preferably to the
+// encoding.TextUnmarshaler
+func applyCustom(v reflect.Value, n Node) (bool, error) {
+	vt := v.Type()
+	if isUnmarshaler(vt) {
+		return applyUnmarshaler(v, n)
+	}
+
+	t := n.Type()
+	structured := t&Primitive == 0 && t&(List|Structure) != 0
+	if isJSONUnmarshaler(vt) && (structured || !isTextUnmarshaler(vt)) {
+		return applyJSONUnmarshaler(v, n)
+	}
+
+	return applyTextUnmarshaler(v, n)
+}
+
 func apply(v reflect.Value, n Node) (bool, error) {
-	// TODO: check here if implements config parser
+	set, err := applyValue(v, n)
+	if ferr := failureOf(n); ferr != nil {
+		return false, errorAt(n, ferr)
+	}
+
+	if err != nil {
+		return set, errorAt(n, err)
+	}
+
+	return set, nil
+}
+
+func applyValue(v reflect.Value, n Node) (bool, error) {
+	switch v.Type() {
+	case durationType:
+		return applyDuration(v, n)
+	case timeType:
+		return applyTime(v, n)
+	}
+
+	if v.CanAddr() && isCustomDecoded(v.Type()) {
+		return applyCustom(v, n)
+	}
 
 	switch v.Kind() {
 	case reflect.Bool:
@@ -587,26 +908,85 @@ func apply(v reflect.Value, n Node) (bool, error) {
 	case reflect.Ptr:
 		return applyPointer(v, n)
 	default:
-		return false, invalidTarget()
+		return false, invalidTarget(v.Type())
 	}
 }
 
-// It may change the target even if fails.
-func Apply(applyTo interface{}, s Source) error {
+// Apply applies the source to the target, then sets the defaults and validates it, see the README for the
+// struct tags. It may change the target even if fails, unless the Transactional option is used.
+func Apply(applyTo interface{}, s Source, o ...Option) error {
 	v := reflect.ValueOf(applyTo)
 	if v.Kind() != reflect.Ptr || v.IsNil() {
-		return invalidTarget()
+		return invalidTarget(reflect.TypeOf(applyTo))
 	}
 
-	n, err := s.Read()
-	if errors.Is(err, ErrNoConfig) {
-		return nil
+	opts := makeOptions(o)
+	if !opts.transactional {
+		return applySource(v, s, opts)
 	}
 
-	if err != nil {
+	staged := reflect.New(v.Type().Elem())
+	deepCopy(staged.Elem(), v.Elem(), make(map[pointerKey]reflect.Value))
+	if err := applySource(staged, s, opts); err != nil {
+		return err
+	}
+
+	v.Elem().Set(staged.Elem())
+	return nil
+}
+
+func applySource(v reflect.Value, s Source, opts options) error {
+	n, err := readFor(s, v.Type(), opts)
+	if err != nil && !errors.Is(err, ErrNoConfig) {
 		return err
 	}
 
-	_, err = apply(v, n)
+	var applyErr error
+	if err != nil {
+		applyErr = applyDefaults(v, opts)
+	} else {
+		if err := checkUnexpected(s, v.Type(), opts); err != nil {
+			return err
+		}
+
+		_, applyErr = apply(v, withOptions(n, opts))
+	}
+
+	if applyErr != nil && !opts.allErrors {
+		return applyErr
+	}
+
+	setDefaults(v, opts)
+	validateErr := validate(v, nil, opts)
+	if !opts.allErrors {
+		return validateErr
+	}
+
+	return sortErrors(joinErrors([]error{applyErr, validateErr}))
+}
+
+// ApplyNode applies a node to the target. It can be used by the Unmarshaler implementations to apply parts
+// of the received node. The options of the received node are preserved, unless overridden.
+func ApplyNode(applyTo interface{}, n Node, o ...Option) error {
+	v := reflect.ValueOf(applyTo)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return invalidTarget(reflect.TypeOf(applyTo))
+	}
+
+	if len(o) > 0 {
+		n = withOptions(n, makeOptions(o))
+	}
+
+	_, err := apply(v, n)
 	return err
 }
+
+// ApplyWithPositional applies the source to the target just like Apply, and returns the positional
+// arguments found in the source, including everything after --.
+func ApplyWithPositional(applyTo interface{}, s Source, o ...Option) ([]string, error) {
+	if err := Apply(applyTo, s, o...); err != nil {
+		return nil, err
+	}
+
+	return positionalOf(s, reflect.TypeOf(applyTo), makeOptions(o))
+}
@@ -2,9 +2,15 @@ package config
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"math"
+	"math/big"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -79,6 +85,12 @@ func TestApplyInvalidTarget(t *testing.T) {
 			t.Error("failed to fail with the right error")
 		}
 	})
+
+	t.Run("nil", func(t *testing.T) {
+		if err := Apply(nil, jsonString("42")); !errors.Is(err, ErrInvalidTarget) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
 }
 
 func TestApplyEmptyConfig(t *testing.T) {
@@ -1158,8 +1170,15 @@ func TestApplyToStruct(t *testing.T) {
 		var o struct{ FooBar int }
 		j := bytes.NewBufferString(`{"fooBar": 21, "foo_bar": 42}`)
 		s := JSON(j)
-		if err := Apply(&o, s); !errors.Is(err, ErrConflictingKeys) {
-			t.Error("failed to fail with the right error")
+		err := Apply(&o, s)
+		if !errors.Is(err, ErrConflictingKeys) {
+			t.Fatal("failed to fail with the right error", err)
+		}
+
+		var e *Error
+		if !errors.As(err, &e) || strings.Join(e.Key, ".") != "foo-bar" ||
+			!strings.Contains(err.Error(), "fooBar, foo_bar") {
+			t.Error("failed to report the conflicting keys", err)
 		}
 	})
 
@@ -1616,3 +1635,287 @@ func TestApplyToInvalidTarget(t *testing.T) {
 		t.Error("failed to fail with the right error", err)
 	}
 }
+
+type testRateLimit struct {
+	requests int
+	period   string
+}
+
+type testRoutes []string
+
+var errTestRateLimit = errors.New("invalid rate limit")
+
+func (r *testRateLimit) UnmarshalConfig(n Node) error {
+	var s string
+	if err := ApplyNode(&s, n); err != nil {
+		return err
+	}
+
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 {
+		return errTestRateLimit
+	}
+
+	i, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return errTestRateLimit
+	}
+
+	r.requests, r.period = i, parts[1]
+	return nil
+}
+
+func (r *testRoutes) UnmarshalConfig(n Node) error {
+	if n.Type()&Structure == 0 {
+		return ErrInvalidInputValue
+	}
+
+	keys := n.Keys()
+	sort.Strings(keys)
+	*r = nil
+	for _, key := range keys {
+		var backend string
+		if err := ApplyNode(&backend, n.Field(key)); err != nil {
+			return err
+		}
+
+		*r = append(*r, key+" -> "+backend)
+	}
+
+	return nil
+}
+
+func TestApplyToUnmarshaler(t *testing.T) {
+	t.Run("primitive", func(t *testing.T) {
+		var o struct {
+			Limit  testRateLimit
+			Limits []*testRateLimit
+		}
+
+		s := jsonString(`{"limit": "1024/30s", "limits": ["1/1s", "2/2s"]}`)
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Limit.requests != 1024 || o.Limit.period != "30s" {
+			t.Error("failed to apply custom unmarshaler", o.Limit)
+		}
+
+		if len(o.Limits) != 2 || o.Limits[0].requests != 1 || o.Limits[1].period != "2s" {
+			t.Error("failed to apply custom unmarshaler in list", o.Limits)
+		}
+	})
+
+	t.Run("fails", func(t *testing.T) {
+		var o struct{ Limit testRateLimit }
+		if err := Apply(&o, iniString("limit = 1024")); !errors.Is(err, errTestRateLimit) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("merged", func(t *testing.T) {
+		var o struct{ Routes testRoutes }
+		s := Merge(
+			iniString("routes.foo = a\nroutes.bar = b"),
+			jsonString(`{"routes": {"foo": "c"}}`),
+		)
+
+		if err := Apply(&o, Strict(s)); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Routes) != 2 || o.Routes[0] != "bar -> b" || o.Routes[1] != "foo -> c" {
+			t.Error("failed to apply custom unmarshaler to merged node", o.Routes)
+		}
+	})
+}
+
+type testLevel int
+
+type testPoint struct{ x, y int }
+
+const (
+	testLevelInfo testLevel = iota
+	testLevelDebug
+)
+
+func (l *testLevel) UnmarshalText(b []byte) error {
+	switch string(b) {
+	case "info":
+		*l = testLevelInfo
+	case "debug":
+		*l = testLevelDebug
+	default:
+		return errors.New("invalid level")
+	}
+
+	return nil
+}
+
+func (p *testPoint) UnmarshalJSON(b []byte) error {
+	var v struct{ X, Y int }
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	p.x, p.y = v.X, v.Y
+	return nil
+}
+
+func TestApplyToTextUnmarshaler(t *testing.T) {
+	type options struct {
+		IP    net.IP
+		Big   *big.Int
+		Level testLevel
+		Point testPoint
+	}
+
+	for _, test := range []struct {
+		title  string
+		source Source
+	}{{
+		title:  "json",
+		source: jsonString(`{"ip": "10.0.0.1", "big": 42, "level": "debug", "point": {"x": 1, "y": 2}}`),
+	}, {
+		title: "ini",
+		source: Merge(
+			iniString("ip = 10.0.0.1\nbig = 42\nlevel = debug"),
+			yamlString("point: {x: 1, \"y\": 2}"),
+		),
+	}, {
+		title:  "toml",
+		source: tomlString("ip = \"10.0.0.1\"\nbig = 42\nlevel = \"debug\"\npoint = {x = 1, y = 2}"),
+	}, {
+		title: "env",
+		source: Merge(
+			Env("", []string{"IP=10.0.0.1", "BIG=42", "LEVEL=debug"}),
+			jsonString(`{"point": {"x": 1, "y": 2}}`),
+		),
+	}, {
+		title: "flags",
+		source: Merge(
+			Flags([]string{"--ip", "10.0.0.1", "--big", "42", "--level", "debug", "foo"}, FlagsDefault),
+			jsonString(`{"point": {"x": 1, "y": 2}}`),
+		),
+	}} {
+		t.Run(test.title, func(t *testing.T) {
+			var o options
+			p, err := ApplyWithPositional(&o, test.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !o.IP.Equal(net.IPv4(10, 0, 0, 1)) ||
+				o.Big == nil || o.Big.Int64() != 42 ||
+				o.Level != testLevelDebug ||
+				o.Point.x != 1 || o.Point.y != 2 {
+				t.Error("failed to apply text unmarshaler", o)
+			}
+
+			if test.title == "flags" && (len(p) != 1 || p[0] != "foo") {
+				t.Error("failed to keep positional argument", p)
+			}
+		})
+	}
+
+	t.Run("invalid", func(t *testing.T) {
+		var o options
+		if err := Apply(&o, jsonString(`{"level": "trace"}`)); !errors.Is(err, ErrInvalidInputValue) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("nil", func(t *testing.T) {
+		o := options{IP: net.IPv4(10, 0, 0, 1)}
+		if err := Apply(&o, jsonString(`{"ip": null}`)); err != nil {
+			t.Fatal(err)
+		}
+
+		if !o.IP.Equal(net.IPv4(10, 0, 0, 1)) {
+			t.Error("failed to handle null as unset", o.IP)
+		}
+	})
+}
+
+func TestApplyTransactional(t *testing.T) {
+	type backend struct {
+		Address string
+		Weight  int
+	}
+
+	type target struct {
+		Port     int
+		Labels   map[string]string
+		Backends []*backend
+		Primary  *backend
+		Limits   struct{ Max int }
+	}
+
+	initial := func() target {
+		b := &backend{Address: "a", Weight: 1}
+		return target{
+			Port:     8080,
+			Labels:   map[string]string{"foo": "bar"},
+			Backends: []*backend{b},
+			Primary:  b,
+			Limits:   struct{ Max int }{Max: 3},
+		}
+	}
+
+	t.Run("failure", func(t *testing.T) {
+		o := initial()
+		s := jsonString(`{
+			"port": 9090,
+			"labels": {"baz": "qux"},
+			"primary": {"address": "b"},
+			"limits": {"max": 5},
+			"backends": [{"weight": "x"}]
+		}`)
+
+		if err := Apply(&o, s, Transactional()); !errors.Is(err, ErrInvalidInputValue) {
+			t.Fatal("failed to fail with the right error", err)
+		}
+
+		if o.Port != 8080 ||
+			len(o.Labels) != 1 ||
+			o.Labels["foo"] != "bar" ||
+			o.Primary.Address != "a" ||
+			o.Limits.Max != 3 ||
+			len(o.Backends) != 1 ||
+			o.Backends[0].Weight != 1 {
+			t.Error("failed to leave the target untouched", o)
+		}
+	})
+
+	t.Run("validation failure", func(t *testing.T) {
+		o := struct {
+			Foo int
+			Bar string `required:"true"`
+		}{Foo: 21, Bar: "baz"}
+
+		if err := Apply(&o, jsonString(`{"foo": 42, "bar": ""}`), Transactional()); !errors.Is(err, ErrValidationFailed) {
+			t.Fatal("failed to fail with the right error", err)
+		}
+
+		if o.Foo != 21 || o.Bar != "baz" {
+			t.Error("failed to leave the target untouched", o)
+		}
+	})
+
+	t.Run("success", func(t *testing.T) {
+		o := initial()
+		s := jsonString(`{"port": 9090, "labels": {"baz": "qux"}, "primary": {"weight": 2}}`)
+		if err := Apply(&o, s, Transactional()); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Port != 9090 ||
+			o.Labels["foo"] != "bar" ||
+			o.Labels["baz"] != "qux" ||
+			o.Primary.Address != "a" ||
+			o.Primary.Weight != 2 ||
+			o.Backends[0] != o.Primary {
+			t.Error("failed to commit the changes", o)
+		}
+	})
+}
@@ -1,28 +1,106 @@
 package config
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
 
+	"github.com/aryszka/config/keys"
+)
+
+// MergeStrategy defines how the values from multiple sources are combined by MergeWith, depending on the
+// type of the target. It can be overridden for a field and its subtree with the merge option of the config
+// tag, e.g. `config:"plugin-dir,merge=append"`, where the possible values are deep, replace and append. The
+// struct fields are always merged key by key, and for the primitive values, the last source wins. The Replace
+// and AppendLists strategies apply to the nested merged sources, too.
+type MergeStrategy int
+
+const (
+
+	// DeepMerge merges the maps key by key, while for the lists, the last source wins. This is the strategy
+	// used by Merge.
+	DeepMerge MergeStrategy = iota
+
+	// Replace takes the maps and the lists from the last source that has them.
+	Replace
+
+	// AppendLists merges the maps like DeepMerge, but concatenates the lists from all the sources, in the
+	// order of the sources.
+	AppendLists
+)
+
+// the keys and the fields are indexed on first access, and the merged fields are created once
 type mergedNode struct {
+	strategy   MergeStrategy
+	layers     []Node
 	value      Node
+	lists      []Node
 	structures []Node
+	unset      bool
+
+	indexOnce sync.Once
+	keys      []string
+	index     map[string][]Node
+
+	fieldsMx sync.Mutex
+	fields   map[string]*mergedNode
+}
+
+// the items of the lists from multiple sources
+type appendedNode struct {
+	lists []Node
+}
+
+// implemented by the nodes whose merging can be changed for a subtree, and depends on the kind of the target,
+// e.g. the merged nodes
+type strategicNode interface {
+	withStrategy(MergeStrategy) Node
+	forKind(reflect.Kind) Node
+}
+
+// implemented by the nodes that can unset a key, e.g. the flags with the no- prefix
+type unsettingNode interface {
+	isUnset() bool
 }
 
 type mergedSource struct {
-	sources []Source
+	strategy MergeStrategy
+	sources  []Source
 }
 
 type overrideSource struct {
 	sources []Source
 }
 
-// TODO: optimize by memoizing, everywhere
+var mergeStrategies = map[string]MergeStrategy{
+	"deep":    DeepMerge,
+	"replace": Replace,
+	"append":  AppendLists,
+}
+
+func invalidMergeStrategy(name string) error {
+	return fmt.Errorf("%w: invalid merge strategy: %s", ErrInvalidTarget, name)
+}
+
+// Merge combines the sources with the DeepMerge strategy.
+func Merge(s ...Source) Source { return MergeWith(DeepMerge, s...) }
+
+// MergeWith combines the sources with the given strategy. The later sources take precedence. A source can
+// unset a key set by the previous sources, with null in JSON and YAML, with the !unset marker in INI, e.g.
+// foo = !unset, with the --no-foo flags, and with empty environment variables when applied with the
+// UnsetEmptyEnv option. The unset keys are handled as if they were not set by the previous sources, and the
+// following sources can set them again.
+func MergeWith(strategy MergeStrategy, s ...Source) Source {
+	return &mergedSource{strategy: strategy, sources: s}
+}
 
-func Merge(s ...Source) Source { return &mergedSource{sources: s} }
+func (s mergedSource) Read() (Node, error) { return s.readTyped(nil, options{}) }
 
-func (s mergedSource) Read() (Node, error) {
+func (s mergedSource) readTyped(t reflect.Type, o options) (Node, error) {
 	var n []Node
 	for _, si := range s.sources {
-		ni, err := si.Read()
+		ni, err := readFor(si, t, o)
 		if ni == nil && err == nil || errors.Is(err, ErrNoConfig) {
 			continue
 		}
@@ -38,33 +116,177 @@ func (s mergedSource) Read() (Node, error) {
 		return nil, ErrNoConfig
 	}
 
-	return mergeNodes(n...), nil
+	return mergeNodes(s.strategy, n...), nil
 }
 
-func mergeNodes(n ...Node) *mergedNode {
-	// TODO: this merging can become interesting with map targets. What's the most expected? The answer
-	// should go into a decision log and documentation
+func (s mergedSource) children() []Source { return s.sources }
+
+func (s mergedSource) positional(t reflect.Type, o options) ([]string, error) {
+	var p []string
+	for _, si := range s.sources {
+		pi, err := positionalOf(si, t, o)
+		if err != nil {
+			return nil, err
+		}
+
+		p = append(p, pi...)
+	}
+
+	return p, nil
+}
 
+func mergeNodes(s MergeStrategy, n ...Node) *mergedNode {
 	var (
 		valueNode  Node
+		lists      []Node
 		structures []Node
+		unset      bool
 	)
 
 	for _, ni := range n {
 		t := ni.Type()
+		if t == Nil {
+			valueNode, lists, structures = nil, nil, nil
+		}
+
+		unset = t == Nil
+
 		if t&(Primitive|List) != 0 {
 			valueNode = ni
 		}
 
+		if t&List != 0 {
+			lists = append(lists, ni)
+		}
+
 		if t&Structure != 0 {
 			structures = append(structures, ni)
 		}
 	}
 
-	return &mergedNode{value: valueNode, structures: structures}
+	return &mergedNode{
+		strategy:   s,
+		layers:     n,
+		value:      valueNode,
+		lists:      lists,
+		structures: structures,
+		unset:      unset,
+	}
+}
+
+func unsetOf(n Node) bool {
+	if un, ok := n.(unsettingNode); ok {
+		return un.isUnset()
+	}
+
+	return false
+}
+
+// the strategy applies to the whole subtree
+func withMergeStrategy(n Node, s MergeStrategy) Node {
+	if sn, ok := n.(strategicNode); ok {
+		return sn.withStrategy(s)
+	}
+
+	return n
+}
+
+// the strategy is applied based on the kind of the target
+func mergedFor(n Node, k reflect.Kind) Node {
+	if sn, ok := n.(strategicNode); ok {
+		return sn.forKind(k)
+	}
+
+	return n
+}
+
+// the strategy is passed to the nested merged layers, so that e.g. the lists of
+// MergeWith(AppendLists, Merge(a, b), c) are all appended
+func (n *mergedNode) forKind(k reflect.Kind) Node {
+	switch {
+	case k == reflect.Slice && n.strategy == AppendLists && len(n.lists) > 0 && n.value.Type()&List != 0:
+		lists := make([]Node, len(n.lists))
+		for i, li := range n.lists {
+			lists[i] = mergedFor(withMergeStrategy(li, n.strategy), k)
+		}
+
+		return appendedNode{lists: lists}
+	case k == reflect.Map && n.strategy == Replace && len(n.structures) > 0:
+		last := withMergeStrategy(n.structures[len(n.structures)-1], n.strategy)
+		return mergeNodes(n.strategy, mergedFor(last, k))
+	default:
+		return n
+	}
+}
+
+func (n *mergedNode) withStrategy(s MergeStrategy) Node {
+	layers := make([]Node, len(n.layers))
+	for i, li := range n.layers {
+		layers[i] = withMergeStrategy(li, s)
+	}
+
+	return mergeNodes(s, layers...)
+}
+
+func (n appendedNode) Type() NodeType         { return List }
+func (n appendedNode) Primitive() interface{} { return nil }
+func (n appendedNode) Keys() []string         { return nil }
+func (n appendedNode) Field(string) Node      { return nil }
+
+func (n appendedNode) Len() int {
+	var l int
+	for _, li := range n.lists {
+		l += li.Len()
+	}
+
+	return l
+}
+
+func (n appendedNode) Item(i int) Node {
+	for _, li := range n.lists {
+		if l := li.Len(); i >= l {
+			i -= l
+			continue
+		}
+
+		return li.Item(i)
+	}
+
+	return nil
+}
+
+func (n appendedNode) origin() origin {
+	return originOf(n.lists[len(n.lists)-1])
+}
+
+func (n appendedNode) failure() error {
+	for _, li := range n.lists {
+		if err := failureOf(li); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (n *mergedNode) isUnset() bool { return n.unset }
+
+// ambiguous only when all the layers are
+func (n *mergedNode) ambiguous() bool {
+	for _, ni := range n.layers {
+		if !ambiguousOf(ni) {
+			return false
+		}
+	}
+
+	return len(n.layers) > 0
 }
 
 func (n *mergedNode) Type() NodeType {
+	if n.unset {
+		return Nil
+	}
+
 	t := Structure
 	if n.value != nil {
 		t |= n.value.Type()
@@ -73,51 +295,138 @@ func (n *mergedNode) Type() NodeType {
 	return t
 }
 
+// the origin of the value, or of the last structure
+func (n *mergedNode) origin() origin {
+	if n.value != nil {
+		return originOf(n.value)
+	}
+
+	if len(n.structures) > 0 {
+		return originOf(n.structures[len(n.structures)-1])
+	}
+
+	return origin{}
+}
+
+func (n *mergedNode) failure() error {
+	if n.value != nil {
+		if err := failureOf(n.value); err != nil {
+			return err
+		}
+	}
+
+	for _, ni := range n.structures {
+		if err := failureOf(ni); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (n *mergedNode) Primitive() interface{} { return n.value.Primitive() }
 func (n *mergedNode) Len() int               { return n.value.Len() }
 func (n *mergedNode) Item(i int) Node        { return n.value.Item(i) }
 
-func (n *mergedNode) Keys() []string {
-	var keys []string
-	found := make(map[string]bool)
-	for _, ni := range n.structures {
-		for _, key := range ni.Keys() {
-			if found[key] {
-				continue
+// the keys of the layers are merged by their canonical form, using the first spelling, e.g. Port from a file
+// and port from the env. When a single layer has multiple spellings of a key, they are indexed separately, so
+// that they are reported as conflicting when applied.
+func (n *mergedNode) buildIndex() {
+	layerKeys := make([][]string, len(n.structures))
+	conflicting := make(map[string]bool)
+	for i, ni := range n.structures {
+		layerKeys[i] = ni.Keys()
+		found := make(map[string]bool)
+		for _, key := range layerKeys[i] {
+			canonical := keys.CanonicalSymbol(key)
+			if found[canonical] {
+				conflicting[canonical] = true
 			}
 
-			found[key] = true
-			keys = append(keys, key)
+			found[canonical] = true
 		}
 	}
 
-	return keys
-}
+	n.index = make(map[string][]Node)
+	spelling := make(map[string]string)
+	for i, ni := range n.structures {
+		for _, key := range layerKeys[i] {
+			indexKey := key
+			if canonical := keys.CanonicalSymbol(key); !conflicting[canonical] {
+				if first, ok := spelling[canonical]; ok {
+					indexKey = first
+				} else {
+					spelling[canonical] = key
+				}
+			}
 
-func (n *mergedNode) Field(key string) Node {
-	var match []Node
-	for _, ni := range n.structures {
-		for _, ki := range ni.Keys() {
-			if ki == key {
-				match = append(match, ni.Field(key))
+			if _, ok := n.index[indexKey]; !ok {
+				n.keys = append(n.keys, indexKey)
 			}
+
+			n.index[indexKey] = append(n.index[indexKey], ni.Field(key))
 		}
 	}
+}
 
-	return mergeNodes(match...)
+// the unset keys are included, and they are skipped when applying, so that the fields are not evaluated
+// before they are needed
+func (n *mergedNode) Keys() []string {
+	n.indexOnce.Do(n.buildIndex)
+	return append([]string(nil), n.keys...)
+}
+
+func (n *mergedNode) Field(key string) Node {
+	n.indexOnce.Do(n.buildIndex)
+	n.fieldsMx.Lock()
+	defer n.fieldsMx.Unlock()
+	if f, ok := n.fields[key]; ok {
+		return f
+	}
+
+	if n.fields == nil {
+		n.fields = make(map[string]*mergedNode)
+	}
+
+	f := mergeNodes(n.strategy, n.index[key]...)
+	n.fields[key] = f
+	return f
 }
 
 func Override(s ...Source) Source { return &overrideSource{sources: s} }
 
-func (s overrideSource) Read() (Node, error) {
+func (s overrideSource) Read() (Node, error) { return s.readTyped(nil, options{}) }
+
+// the last source that has config
+func (s overrideSource) selected(t reflect.Type, o options) (Source, Node, error) {
 	for i := len(s.sources) - 1; i >= 0; i-- {
-		n, err := s.sources[i].Read()
+		n, err := readFor(s.sources[i], t, o)
 		if n == nil && err == nil || errors.Is(err, ErrNoConfig) {
 			continue
 		}
 
-		return n, err
+		return s.sources[i], n, err
+	}
+
+	return nil, nil, ErrNoConfig
+}
+
+func (s overrideSource) readTyped(t reflect.Type, o options) (Node, error) {
+	_, n, err := s.selected(t, o)
+	return n, err
+}
+
+func (s overrideSource) children() []Source { return s.sources }
+
+func (s overrideSource) positional(t reflect.Type, o options) ([]string, error) {
+	si, _, err := s.selected(t, o)
+	if errors.Is(err, ErrNoConfig) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
 	}
 
-	return nil, ErrNoConfig
+	return positionalOf(si, t, o)
 }
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// every layer has the same keys, and sets the leaves it owns
+func benchmarkLayers(layers, width int) []Source {
+	var s []Source
+	for l := 0; l < layers; l++ {
+		root := make(map[string]interface{})
+		for i := 0; i < width; i++ {
+			middle := make(map[string]interface{})
+			for j := 0; j < width; j++ {
+				leaves := make(map[string]interface{})
+				for k := 0; k < width; k++ {
+					if (i+j+k)%layers == l {
+						leaves[fmt.Sprintf("leaf%d", k)] = l
+					}
+				}
+
+				middle[fmt.Sprintf("middle%d", j)] = leaves
+			}
+
+			root[fmt.Sprintf("root%d", i)] = middle
+		}
+
+		b, err := json.Marshal(root)
+		if err != nil {
+			panic(err)
+		}
+
+		s = append(s, jsonString(string(b)))
+	}
+
+	return s
+}
+
+func BenchmarkApplyMerge(b *testing.B) {
+	for _, layers := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("layers=%d", layers), func(b *testing.B) {
+			s := Merge(benchmarkLayers(layers, 10)...)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				var o map[string]map[string]map[string]int
+				if err := Apply(&o, s); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkMergedNodeField(b *testing.B) {
+	for _, layers := range []int{1, 10, 100} {
+		b.Run(fmt.Sprintf("layers=%d", layers), func(b *testing.B) {
+			n, err := Merge(benchmarkLayers(layers, 10)...).Read()
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				n.Field("root3").Field("middle7").Field("leaf5").Primitive()
+			}
+		})
+	}
+}
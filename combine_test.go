@@ -3,6 +3,7 @@ package config
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"testing"
 )
 
@@ -23,6 +24,10 @@ func iniString(i string) Source {
 	return INI(bytes.NewBufferString(i))
 }
 
+func yamlString(y string) Source {
+	return YAML(bytes.NewBufferString(y))
+}
+
 func TestMerge(t *testing.T) {
 	t.Run("invalid no node", func(t *testing.T) {
 		var o struct{ Foo int }
@@ -161,6 +166,40 @@ func TestMerge(t *testing.T) {
 			t.Error("failed to merge sources")
 		}
 	})
+
+	t.Run("different spellings of the keys", func(t *testing.T) {
+		var o struct {
+			Port          int
+			ListenAddress string
+			Backend       struct{ MaxConns int }
+		}
+
+		s := Merge(
+			jsonString(`{"Port": 1, "listenAddress": "foo", "backend": {"maxConns": 2}}`),
+			Env("myapp", []string{"MYAPP_PORT=3", "MYAPP_LISTEN_ADDRESS=bar"}),
+			Flags([]string{"--backend.max-conns", "4"}, FlagsDefault),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Port != 3 || o.ListenAddress != "bar" || o.Backend.MaxConns != 4 {
+			t.Error("failed to merge the keys", o)
+		}
+	})
+
+	t.Run("different spellings in the same source", func(t *testing.T) {
+		var o struct{ FooBar int }
+		s := Merge(
+			jsonString(`{"fooBar": 1, "foo_bar": 2}`),
+			Env("myapp", []string{"MYAPP_FOO_BAR=3"}),
+		)
+
+		if err := Apply(&o, s); !errors.Is(err, ErrConflictingKeys) {
+			t.Error("failed to fail with conflicting keys", err)
+		}
+	})
 }
 
 func TestOverride(t *testing.T) {
@@ -258,3 +297,361 @@ func TestOverride(t *testing.T) {
 		}
 	})
 }
+
+func TestMergeConcurrent(t *testing.T) {
+	type target struct {
+		Foo struct {
+			Bar int
+			Baz []string
+		}
+		Qux string
+	}
+
+	s := Merge(
+		jsonString(`{"foo": {"bar": 21}, "qux": "quux"}`),
+		iniString("[foo]\nbar = 42\nbaz = a\nbaz = b"),
+		Env("myapp", []string{"MYAPP_QUX=corge"}),
+	)
+
+	n, err := s.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const count = 16
+	errs := make(chan error, count)
+	for i := 0; i < count; i++ {
+		go func() {
+			var o target
+			if err := ApplyNode(&o, n); err != nil {
+				errs <- err
+				return
+			}
+
+			if o.Foo.Bar != 42 || len(o.Foo.Baz) != 2 || o.Qux != "corge" {
+				errs <- fmt.Errorf("unexpected result: %v", o)
+				return
+			}
+
+			errs <- nil
+		}()
+	}
+
+	for i := 0; i < count; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestMergeStrategies(t *testing.T) {
+	type target struct {
+		Name   string
+		Dirs   []string
+		Labels map[string]string
+		Port   int
+	}
+
+	layers := func() []Source {
+		return []Source{
+			iniString("name = foo\nport = 80\ndirs = /etc/a\ndirs = /etc/b\n[labels]\nfoo = 1\nbar = 2"),
+			jsonString(`{"dirs": ["/home/c"], "labels": {"bar": "3"}, "port": 8080}`),
+		}
+	}
+
+	t.Run("deep merge", func(t *testing.T) {
+		var o target
+		if err := Apply(&o, MergeWith(DeepMerge, layers()...)); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Dirs) != 1 || o.Dirs[0] != "/home/c" ||
+			len(o.Labels) != 2 || o.Labels["foo"] != "1" || o.Labels["bar"] != "3" || o.Port != 8080 {
+			t.Error("failed to merge", o)
+		}
+	})
+
+	t.Run("replace", func(t *testing.T) {
+		var o target
+		if err := Apply(&o, MergeWith(Replace, layers()...)); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Name != "foo" || len(o.Dirs) != 1 || o.Dirs[0] != "/home/c" ||
+			len(o.Labels) != 1 || o.Labels["bar"] != "3" || o.Port != 8080 {
+			t.Error("failed to merge", o)
+		}
+	})
+
+	t.Run("append lists", func(t *testing.T) {
+		var o target
+		if err := Apply(&o, MergeWith(AppendLists, layers()...)); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Name != "foo" || len(o.Dirs) != 3 || o.Dirs[0] != "/etc/a" || o.Dirs[1] != "/etc/b" ||
+			o.Dirs[2] != "/home/c" || len(o.Labels) != 2 || o.Port != 8080 {
+			t.Error("failed to merge", o)
+		}
+	})
+
+	t.Run("append lists with scalars", func(t *testing.T) {
+		var o struct{ Port int }
+		s := MergeWith(
+			AppendLists,
+			iniString("port = 1"),
+			iniString("port = 2"),
+			Env("myapp", []string{"MYAPP_PORT=3"}),
+			Flags([]string{"--port", "4"}, FlagsDefault),
+		)
+
+		if err := Apply(&o, s); err != nil || o.Port != 4 {
+			t.Error("failed to take the last value", o.Port, err)
+		}
+	})
+
+	t.Run("replace nested maps", func(t *testing.T) {
+		var o struct {
+			Backend struct {
+				Host   string
+				Labels map[string]string
+			}
+		}
+
+		s := MergeWith(
+			Replace,
+			iniString("[backend]\nhost = foo\nlabels.foo = 1"),
+			jsonString(`{"backend": {"labels": {"bar": "2"}}}`),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Backend.Host != "foo" || len(o.Backend.Labels) != 1 || o.Backend.Labels["bar"] != "2" {
+			t.Error("failed to replace the map only", o)
+		}
+	})
+
+	t.Run("field override", func(t *testing.T) {
+		var o struct {
+			PluginDir []string          `config:"plugin-dir,merge=append"`
+			Labels    map[string]string `config:",merge=replace"`
+			Other     []string
+		}
+
+		s := Merge(
+			iniString("plugin-dir = /etc/plugins\nother = foo\n[labels]\nfoo = 1"),
+			jsonString(`{"plugin-dir": ["/home/plugins"], "other": ["bar"], "labels": {"bar": "2"}}`),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.PluginDir) != 2 || o.PluginDir[0] != "/etc/plugins" || o.PluginDir[1] != "/home/plugins" {
+			t.Error("failed to append the lists", o.PluginDir)
+		}
+
+		if len(o.Labels) != 1 || o.Labels["bar"] != "2" {
+			t.Error("failed to replace the map", o.Labels)
+		}
+
+		if len(o.Other) != 1 || o.Other[0] != "bar" {
+			t.Error("failed to keep the default strategy", o.Other)
+		}
+	})
+
+	t.Run("field override with options", func(t *testing.T) {
+		var o struct {
+			Dirs []string `config:",merge=append"`
+		}
+
+		if err := Apply(&o, Merge(layers()...), AllErrors()); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Dirs) != 3 {
+			t.Error("failed to append the lists", o.Dirs)
+		}
+	})
+
+	t.Run("invalid strategy", func(t *testing.T) {
+		var o struct {
+			Dirs []string `config:",merge=shuffle"`
+		}
+
+		if err := Apply(&o, Merge(layers()...)); !errors.Is(err, ErrInvalidTarget) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		var o target
+		s := MergeWith(AppendLists, Merge(layers()...), jsonString(`{"dirs": ["/home/d"]}`))
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Dirs) != 4 || o.Dirs[0] != "/etc/a" || o.Dirs[1] != "/etc/b" || o.Dirs[2] != "/home/c" ||
+			o.Dirs[3] != "/home/d" {
+			t.Error("failed to append the lists", o.Dirs)
+		}
+	})
+
+	t.Run("nested replace", func(t *testing.T) {
+		var o target
+		s := MergeWith(Replace, Merge(layers()...), jsonString(`{"port": 9090}`))
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Labels) != 1 || o.Labels["bar"] != "3" || o.Port != 9090 {
+			t.Error("failed to replace the map", o)
+		}
+	})
+
+	t.Run("nested field override", func(t *testing.T) {
+		var o struct {
+			Dirs []string `config:",merge=append"`
+		}
+
+		s := Merge(
+			Merge(iniString("dirs = /etc/a"), iniString("dirs = /etc/b")),
+			Env("myapp", []string{"MYAPP_NAME=foo"}),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Dirs) != 2 || o.Dirs[0] != "/etc/a" || o.Dirs[1] != "/etc/b" {
+			t.Error("failed to append the lists", o.Dirs)
+		}
+	})
+}
+
+func TestMergeUnset(t *testing.T) {
+	type target struct {
+		Foo    int
+		Dirs   []string
+		Labels map[string]string
+	}
+
+	base := func() Source {
+		return iniString("foo = 42\ndirs = /etc/a\ndirs = /etc/b\n[labels]\nbar = 1\nbaz = 2")
+	}
+
+	check := func(t *testing.T, s Source, o ...Option) target {
+		v := target{Foo: 21, Dirs: []string{"/default"}}
+		if err := Apply(&v, s, o...); err != nil {
+			t.Fatal(err)
+		}
+
+		return v
+	}
+
+	t.Run("json null", func(t *testing.T) {
+		o := check(t, Merge(base(), jsonString(`{"foo": null, "dirs": null, "labels": {"bar": null}}`)))
+		if o.Foo != 21 || len(o.Dirs) != 1 || o.Dirs[0] != "/default" || len(o.Labels) != 1 || o.Labels["baz"] != "2" {
+			t.Error("failed to unset the keys", o)
+		}
+	})
+
+	t.Run("yaml null", func(t *testing.T) {
+		o := check(t, Merge(base(), yamlString("foo: null\nlabels:\n  baz: ~")))
+		if o.Foo != 21 || len(o.Dirs) != 2 || len(o.Labels) != 1 || o.Labels["bar"] != "1" {
+			t.Error("failed to unset the keys", o)
+		}
+	})
+
+	t.Run("ini marker", func(t *testing.T) {
+		o := check(t, Merge(base(), iniString("foo = !unset\nlabels = !unset")))
+		if o.Foo != 21 || len(o.Dirs) != 2 || len(o.Labels) != 0 {
+			t.Error("failed to unset the keys", o)
+		}
+	})
+
+	t.Run("ini quoted marker", func(t *testing.T) {
+		var o struct{ Foo string }
+		if err := Apply(&o, Merge(iniString("foo = bar"), iniString(`foo = "!unset"`))); err != nil || o.Foo != "!unset" {
+			t.Error("failed to apply the quoted value", o, err)
+		}
+	})
+
+	t.Run("flags", func(t *testing.T) {
+		o := check(t, Merge(base(), Flags([]string{"--no-dirs", "--no-labels.bar", "qux"}, FlagsDefault)))
+		if o.Foo != 42 || len(o.Dirs) != 1 || o.Dirs[0] != "/default" || len(o.Labels) != 1 || o.Labels["baz"] != "2" {
+			t.Error("failed to unset the keys", o)
+		}
+	})
+
+	t.Run("flags with no field", func(t *testing.T) {
+		var o struct {
+			NoColor bool
+			Color   bool
+		}
+
+		s := Merge(jsonString(`{"color": true}`), Flags([]string{"--no-color"}, FlagsDefault))
+		if err := Apply(&o, s); err != nil || !o.NoColor || !o.Color {
+			t.Error("failed to apply the flag to the field", o, err)
+		}
+	})
+
+	t.Run("empty env", func(t *testing.T) {
+		env := []string{"MYAPP_FOO=", "MYAPP_LABELS_BAR="}
+		o := check(t, Merge(base(), Env("myapp", env)), UnsetEmptyEnv())
+		if o.Foo != 21 || len(o.Dirs) != 2 || len(o.Labels) != 1 || o.Labels["baz"] != "2" {
+			t.Error("failed to unset the keys", o)
+		}
+	})
+
+	t.Run("empty env without the option", func(t *testing.T) {
+		var o struct{ Bar string }
+		if err := Apply(&o, Merge(jsonString(`{"bar": "baz"}`), Env("myapp", []string{"MYAPP_BAR="}))); err != nil || o.Bar != "" {
+			t.Error("failed to apply the empty value", o, err)
+		}
+	})
+
+	t.Run("set again", func(t *testing.T) {
+		o := check(t, MergeWith(
+			AppendLists,
+			base(),
+			jsonString(`{"dirs": null}`),
+			jsonString(`{"dirs": ["/home/c"]}`),
+		))
+
+		if len(o.Dirs) != 1 || o.Dirs[0] != "/home/c" {
+			t.Error("failed to set the list again", o.Dirs)
+		}
+	})
+
+	t.Run("without merge", func(t *testing.T) {
+		o := check(t, Flags([]string{"--no-foo", "--no-dirs", "--no-labels"}, FlagsDefault))
+		if o.Foo != 21 || len(o.Dirs) != 1 || o.Labels != nil {
+			t.Error("failed to ignore the unset keys", o)
+		}
+	})
+
+	t.Run("null without merge", func(t *testing.T) {
+		var o struct {
+			Foo *int
+			Bar []string
+		}
+
+		if err := Apply(&o, jsonString(`{"foo": null, "bar": null}`)); err != nil || o.Foo != nil || o.Bar != nil {
+			t.Error("failed to ignore the unset keys", o, err)
+		}
+	})
+
+	t.Run("keys and fields", func(t *testing.T) {
+		n, err := Merge(base(), jsonString(`{"foo": null}`)).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if n.Field("foo").Type() != Nil || !unsetOf(n.Field("foo")) {
+			t.Error("failed to unset the field")
+		}
+	})
+}
@@ -1,13 +1,18 @@
 package config
 
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	cfg "github.com/aryszka/config"
+)
+
 // TODO:
-// - how to deal with the annotations for yaml and json?
-// - how to control file types
-// - how to handle unsupported keys in files
 // - how to handle which possible file sources are allowed
-// - support env prefix
-// - with flag parsing, we need to know the type in advance, because of the last non-positional bool flag
-// doesn't necessarily have a value
 
 type Test struct {
 	fileSystem map[string]string
@@ -18,6 +23,12 @@ type Test struct {
 type Settings struct {
 	base         string
 	fileFlagName string
+	envPrefix    string
+	strict       bool
+	tagNames     []string
+	target       interface{}
+	help         string
+	flags        cfg.Source
 	Test         *Test
 }
 
@@ -47,6 +58,175 @@ func (s *Settings) SetFileFlagName(n string) {
 	s.fileFlagName = n
 }
 
+func (s *Settings) SetEnvPrefix(p string) {
+	s.envPrefix = p
+}
+
+// SetStrict makes applying fail when the config files contain keys that the target has no fields for.
+func (s *Settings) SetStrict(strict bool) {
+	s.strict = strict
+}
+
+// SetTagNames sets the struct tags, e.g. json or yaml, that the key names are taken from when a field has no
+// config tag.
+func (s *Settings) SetTagNames(names ...string) {
+	s.tagNames = names
+}
+
+func (s *Settings) options() []cfg.Option {
+	if len(s.tagNames) == 0 {
+		return nil
+	}
+
+	return []cfg.Option{cfg.TagNames(s.tagNames...)}
+}
+
+func (s *Settings) basePath() string {
+	if s.base != "" {
+		return s.base
+	}
+
+	return filepath.Base(os.Args[0])
+}
+
+func (s *Settings) args() []string {
+	if s.Test == nil || s.Test.flags == nil {
+		return os.Args[1:]
+	}
+
+	var names []string
+	for name := range s.Test.flags {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	var args []string
+	for _, name := range names {
+		args = append(args, fmt.Sprintf("--%s=%s", name, s.Test.flags[name]))
+	}
+
+	return args
+}
+
+func (s *Settings) environ() []string {
+	if s.Test == nil || s.Test.env == nil {
+		return nil
+	}
+
+	e := []string{}
+	for name, value := range s.Test.env {
+		e = append(e, fmt.Sprintf("%s=%s", name, value))
+	}
+
+	return e
+}
+
+// the test file system contains the paths with the $HOME and $BINDIR variables not expanded
+func (s *Settings) readFile(name string) ([]byte, error) {
+	if s.Test != nil && s.Test.fileSystem != nil {
+		content, ok := s.Test.fileSystem[name]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+
+		return []byte(content), nil
+	}
+
+	return readFile(name)
+}
+
+func (s *Settings) file(name string) cfg.Source {
+	f := cfg.FileWith(name, s.readFile)
+	if s.strict {
+		return cfg.Strict(f)
+	}
+
+	return f
+}
+
+func (s *Settings) customFile(args, env cfg.Source) (cfg.Source, error) {
+	if s.fileFlagName == "" {
+		return nil, nil
+	}
+
+	n, err := cfg.Merge(env, args).Read()
+	if errors.Is(err, cfg.ErrNoConfig) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	f, ok := field(n, s.fileFlagName)
+	if !ok {
+		return nil, nil
+	}
+
+	var name string
+	if err := cfg.Apply(&name, nodeSource{node: f}); err != nil {
+		return nil, err
+	}
+
+	if name == "" {
+		return nil, nil
+	}
+
+	return s.file(name), nil
+}
+
+// Apply applies the configuration to o from the following sources, in the order of increasing precedence:
+// files, environment variables and command line flags. The files are merged in the order of /etc/<base>,
+// $HOME/.config/<base>, $BINDIR/.config and the file set by the file flag.
 func (s *Settings) Apply(o interface{}) error {
-	return nil
+	base := s.basePath()
+	args := cfg.Flags(s.args(), cfg.FlagsDefault)
+	s.target, s.flags = o, args
+
+	// the help is generated before applying, so that it shows the defaults instead of the input
+	s.help = cfg.Help(o, s.envPrefix, cfg.FlagsDefault, s.options()...)
+	env := cfg.Env(s.envPrefix, s.environ())
+	files := []cfg.Source{
+		s.file(path.Join("/etc", base)),
+		s.file(path.Join("$HOME/.config", base)),
+		s.file("$BINDIR/.config"),
+	}
+
+	custom, err := s.customFile(args, env)
+	if err != nil {
+		return err
+	}
+
+	if custom != nil {
+		files = append(files, custom)
+	}
+
+	return cfg.Apply(o, cfg.Merge(cfg.Merge(files...), env, args), s.options()...)
+}
+
+// Help returns the usage text of the last applied target, with the defaults that it had before applying.
+func (s *Settings) Help() string {
+	if s.target == nil {
+		return cfg.Help(nil, s.envPrefix, cfg.FlagsDefault, s.options()...)
+	}
+
+	return s.help
+}
+
+// HelpFor returns the error message followed by the usage text of the last applied target.
+func (s *Settings) HelpFor(err error) string {
+	if err == nil {
+		return s.Help()
+	}
+
+	return fmt.Sprintf("%v\n\n%s", err, s.Help())
+}
+
+// HelpRequested tells whether the -h or --help flag was set during the last apply.
+func (s *Settings) HelpRequested() bool {
+	if s.flags == nil {
+		return false
+	}
+
+	return cfg.HelpRequested(s.target, s.flags, s.options()...)
 }
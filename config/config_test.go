@@ -1,10 +1,14 @@
 package config
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
 
-func TestOverride(t *testing.T) {
-	t.Skip()
+	cfg "github.com/aryszka/config"
+)
 
+func TestOverride(t *testing.T) {
 	s := New()
 	s.SetBasePath("app/config")
 	s.Test.SetFileSystem(testFileSystem{
@@ -43,29 +47,171 @@ func TestOverride(t *testing.T) {
 	})
 
 	type options struct {
-		foo, bar, baz, qux int
+		Foo, Bar, Baz, Qux int
 	}
 
 	var o options
-	o.qux = 16
+	o.Qux = 16
 
 	if err := s.Apply(&o); err != nil {
 		t.Fatal(err)
 	}
 
-	if o.foo != 15 {
-		t.Error("failed to take the value of 'foo' from the command line flags", o.foo)
+	if o.Foo != 15 {
+		t.Error("failed to take the value of 'foo' from the command line flags", o.Foo)
+	}
+
+	if o.Bar != 14 {
+		t.Error("failed to take the value of 'bar' from the environment", o.Bar)
+	}
+
+	if o.Baz != 12 {
+		t.Error("failed to take the value of 'baz' from the alternative config file", o.Baz)
 	}
 
-	if o.bar != 14 {
-		t.Error("failed to take the value of 'bar' from the environment", o.bar)
+	if o.Qux != 16 {
+		t.Error("failed to leave the default value of 'qux'", o.Qux)
 	}
+}
+
+func TestFileLookup(t *testing.T) {
+	s := New()
+	s.SetBasePath("app/config")
+	s.SetEnvPrefix("app")
+	s.Test.SetFileSystem(testFileSystem{
+		"/etc/app/config":          "foo = 1\nbar = 2",
+		"$HOME/.config/app/config": "foo = 3",
+	})
+	s.Test.SetEnv(map[string]string{"BAR": "4"})
+	s.Test.SetFlags(map[string]string{})
+
+	var o struct{ Foo, Bar int }
+	if err := s.Apply(&o); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.Foo != 3 {
+		t.Error("failed to take the value of 'foo' from the home config", o.Foo)
+	}
+
+	if o.Bar != 2 {
+		t.Error("failed to take the value of 'bar' from the /etc config, ignoring the env without prefix", o.Bar)
+	}
+}
+
+func TestFileFromEnv(t *testing.T) {
+	s := New()
+	s.SetBasePath("app/config")
+	s.SetFileFlagName("config-file")
+	s.Test.SetFileSystem(testFileSystem{
+		"/etc/app/config": "foo = 1",
+		".alt":            "foo = 2",
+	})
+	s.Test.SetEnv(map[string]string{"CONFIG_FILE": ".alt"})
+	s.Test.SetFlags(map[string]string{})
+
+	var o struct{ Foo int }
+	if err := s.Apply(&o); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.Foo != 2 {
+		t.Error("failed to take the value of 'foo' from the file set in the env", o.Foo)
+	}
+}
+
+func TestBinDirFile(t *testing.T) {
+	s := New()
+	s.SetBasePath("myapp")
+	s.Test.SetFileSystem(testFileSystem{
+		"/etc/myapp":      "foo = 1\nbar = 2",
+		"$BINDIR/.config": "foo = 3",
+		"$BINDIR/.myapp":  "bar = 4",
+	})
+	s.Test.SetEnv(map[string]string{})
+	s.Test.SetFlags(map[string]string{})
+
+	var o struct{ Foo, Bar int }
+	if err := s.Apply(&o); err != nil {
+		t.Fatal(err)
+	}
+
+	if o.Foo != 3 || o.Bar != 2 {
+		t.Error("failed to take the values from $BINDIR/.config", o)
+	}
+}
+
+func TestZeroSettings(t *testing.T) {
+	var (
+		s Settings
+		o struct{ ZeroSettingsTestValue int }
+	)
+
+	s.Test = &Test{}
+	s.Test.SetEnv(map[string]string{})
+	s.Test.SetFlags(map[string]string{})
+	if err := s.Apply(&o); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	s := New()
+	s.SetEnvPrefix("app")
+	s.Test.SetFileSystem(testFileSystem{})
+	s.Test.SetEnv(map[string]string{})
+	s.Test.SetFlags(map[string]string{"help": "true", "foo": "3"})
+
+	o := struct {
+		Foo int `help:"just foo"`
+	}{Foo: 42}
+
+	if err := s.Apply(&o); err != nil {
+		t.Fatal(err)
+	}
+
+	if !s.HelpRequested() {
+		t.Error("failed to detect help request")
+	}
+
+	h := s.HelpFor(errors.New("test error"))
+	if !strings.HasPrefix(h, "test error") || !strings.Contains(h, "--foo int") || !strings.Contains(h, "APP_FOO") {
+		t.Error("failed to generate help", h)
+	}
+	if !strings.Contains(h, "default: 42") || strings.Contains(h, "default: 3") {
+		t.Error("failed to show the default", h)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	s := New()
+	s.SetBasePath("app/config")
+	s.SetStrict(true)
+	s.Test.SetFileSystem(testFileSystem{"/etc/app/config": "tls-cer = foo"})
+	s.Test.SetEnv(map[string]string{"BAR": "baz"})
+	s.Test.SetFlags(map[string]string{})
+
+	var o struct{ TLSCert string }
+	if err := s.Apply(&o); !errors.Is(err, cfg.ErrUnexpectedKeys) {
+		t.Error("failed to fail with the right error", err)
+	}
+}
+
+func TestFileNameInErrors(t *testing.T) {
+	s := New()
+	s.SetBasePath("app/config")
+	s.Test.SetFileSystem(testFileSystem{"/etc/app/config": "foo = bar"})
+	s.Test.SetEnv(map[string]string{})
+	s.Test.SetFlags(map[string]string{})
 
-	if o.baz != 12 {
-		t.Error("failed to take the value of 'baz' from the alternative config file", o.baz)
+	var o struct{ Foo int }
+	if err := s.Apply(&o); err == nil || !strings.Contains(err.Error(), "source=/etc/app/config") {
+		t.Error("failed to fail with the file name", err)
 	}
 
-	if o.qux != 16 {
-		t.Error("failed to leave the default value of 'qux'", o.qux)
+	s.SetStrict(true)
+	var so struct{ Bar int }
+	if err := s.Apply(&so); err == nil || !strings.Contains(err.Error(), "source=/etc/app/config") {
+		t.Error("failed to fail with the file name", err)
 	}
 }
@@ -1,6 +1,65 @@
 package config
 
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	cfg "github.com/aryszka/config"
+	"github.com/aryszka/config/keys"
+)
+
 type source struct {
 	terminalsParsed bool
 	data            map[string]interface{}
 }
+
+type nodeSource struct {
+	node cfg.Node
+}
+
+func expandPath(name string) string {
+	return os.Expand(name, func(v string) string {
+		switch v {
+		case "HOME":
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return ""
+			}
+
+			return home
+		case "BINDIR":
+			exe, err := os.Executable()
+			if err != nil {
+				return filepath.Dir(os.Args[0])
+			}
+
+			return filepath.Dir(exe)
+		default:
+			return os.Getenv(v)
+		}
+	})
+}
+
+func readFile(name string) ([]byte, error) {
+	return ioutil.ReadFile(expandPath(name))
+}
+
+func (s nodeSource) Read() (cfg.Node, error) {
+	return s.node, nil
+}
+
+func field(n cfg.Node, key string) (cfg.Node, bool) {
+	if n.Type()&cfg.Structure == 0 {
+		return nil, false
+	}
+
+	canonical := keys.CanonicalSymbol(key)
+	for _, k := range n.Keys() {
+		if keys.CanonicalSymbol(k) == canonical {
+			return n.Field(k), true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,81 @@
+package config
+
+import "reflect"
+
+// a pointer and the first field of the struct that it points to have the same address
+type pointerKey struct {
+	typ     reflect.Type
+	address uintptr
+}
+
+// copies the value deeply, following the pointers, maps, lists and interfaces, so that applying to the copy
+// doesn't change the original. The unexported fields are copied shallowly, because they can't be set, but
+// they are not changed by apply either, only by the custom unmarshalers and hooks of the target.
+func deepCopy(dst, src reflect.Value, visited map[pointerKey]reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+
+		key := pointerKey{typ: src.Type(), address: src.Pointer()}
+		if p, ok := visited[key]; ok {
+			dst.Set(p)
+			return
+		}
+
+		p := reflect.New(src.Type().Elem())
+		visited[key] = p
+		deepCopy(p.Elem(), src.Elem(), visited)
+		dst.Set(p)
+	case reflect.Interface:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+
+		e := reflect.New(src.Elem().Type()).Elem()
+		deepCopy(e, src.Elem(), visited)
+		dst.Set(e)
+	case reflect.Map:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+
+		m := reflect.MakeMapWithSize(src.Type(), src.Len())
+		for _, key := range src.MapKeys() {
+			e := reflect.New(src.Type().Elem()).Elem()
+			deepCopy(e, src.MapIndex(key), visited)
+			m.SetMapIndex(key, e)
+		}
+
+		dst.Set(m)
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+
+		l := reflect.MakeSlice(src.Type(), src.Len(), src.Cap())
+		for i := 0; i < src.Len(); i++ {
+			deepCopy(l.Index(i), src.Index(i), visited)
+		}
+
+		dst.Set(l)
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			deepCopy(dst.Index(i), src.Index(i), visited)
+		}
+	case reflect.Struct:
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				deepCopy(dst.Field(i), src.Field(i), visited)
+			}
+		}
+	default:
+		dst.Set(src)
+	}
+}
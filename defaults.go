@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aryszka/config/ini"
+)
+
+func invalidDefault(name string, err error) error {
+	return fmt.Errorf("%w: invalid default value of field %s: %v", ErrInvalidTarget, name, err)
+}
+
+// the default values are parsed the same way as the values from the ini files. The default values of lists
+// are separated by commas.
+func defaultNode(t reflect.Type, value string) Node {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	values := []string{value}
+	if t.Kind() == reflect.Slice && !isCustomDecoded(t) {
+		values = strings.Split(value, ",")
+	}
+
+	return iniNode{ini: &ini.Node{Values: values}}
+}
+
+// applies the default value of a field, or when it has none, the default values of its fields
+func applyDefault(v reflect.Value, f reflect.StructField, o options) error {
+	value, ok := f.Tag.Lookup("default")
+	if !ok {
+		return applyDefaults(v, o)
+	}
+
+	if _, err := apply(v, withTag(defaultNode(f.Type, value), f.Tag)); err != nil {
+		return invalidDefault(f.Name, err)
+	}
+
+	return nil
+}
+
+// applies the default values taken from the struct tags. Nil pointers are not allocated for the defaults of
+// the fields of the structures that they point to.
+func applyDefaults(v reflect.Value, o options) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+
+		v = v.Elem()
+	}
+
+	t := v.Type()
+	if t.Kind() != reflect.Struct || t == timeType || isCustomDecoded(t) {
+		return nil
+	}
+
+	for _, f := range structFields(t, o) {
+		if err := applyDefault(v.FieldByIndex(f.field.Index), f.field, o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
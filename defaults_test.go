@@ -0,0 +1,114 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefaults(t *testing.T) {
+	type target struct {
+		Port    int           `default:"8080"`
+		Host    string        `default:"localhost"`
+		Verbose *bool         `default:"true"`
+		Timeout time.Duration `default:"3s"`
+		Tags    []string      `default:"foo,bar"`
+		Nested  struct {
+			Level  float64 `default:"0.5"`
+			Prefix string
+		}
+	}
+
+	t.Run("not set", func(t *testing.T) {
+		var o target
+		if err := Apply(&o, jsonString(`{"host": "example.org", "nested": {"prefix": "baz"}}`)); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Port != 8080 ||
+			o.Host != "example.org" ||
+			o.Verbose == nil || !*o.Verbose ||
+			o.Timeout != 3*time.Second ||
+			len(o.Tags) != 2 || o.Tags[0] != "foo" || o.Tags[1] != "bar" ||
+			o.Nested.Level != 0.5 || o.Nested.Prefix != "baz" {
+			t.Error("failed to apply defaults", o)
+		}
+	})
+
+	t.Run("no config", func(t *testing.T) {
+		var o target
+		if err := Apply(&o, Env("myapp", []string{"OTHERAPP_PORT=42"})); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Port != 8080 || o.Nested.Level != 0.5 {
+			t.Error("failed to apply defaults without config", o)
+		}
+	})
+
+	t.Run("set", func(t *testing.T) {
+		var o target
+		s := Merge(iniString("port = 42"), Env("myapp", []string{"MYAPP_NESTED_LEVEL=1.5"}))
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Port != 42 || o.Nested.Level != 1.5 || o.Host != "localhost" {
+			t.Error("failed to prefer the config over the defaults", o)
+		}
+	})
+
+	t.Run("list items", func(t *testing.T) {
+		var o struct {
+			Items []struct {
+				Name string
+				Size int `default:"3"`
+			}
+		}
+
+		if err := Apply(&o, jsonString(`{"items": [{"name": "foo"}, {"name": "bar", "size": 5}]}`)); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Items) != 2 || o.Items[0].Size != 3 || o.Items[1].Size != 5 {
+			t.Error("failed to apply defaults to list items", o)
+		}
+	})
+
+	t.Run("nil pointer not allocated", func(t *testing.T) {
+		var o struct {
+			Foo *struct {
+				Bar int `default:"42"`
+			}
+		}
+
+		if err := Apply(&o, jsonString(`{"baz": 1}`)); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != nil {
+			t.Error("failed to keep the pointer nil", o.Foo)
+		}
+	})
+
+	t.Run("invalid default", func(t *testing.T) {
+		var o struct {
+			Foo int `default:"bar"`
+		}
+
+		if err := Apply(&o, jsonString(`{}`)); !errors.Is(err, ErrInvalidTarget) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		o := struct {
+			Port int `default:"8080"`
+		}{Port: 42}
+
+		if h := Help(&o, "", FlagsDefault); !strings.Contains(h, "default: 8080") {
+			t.Error("failed to show the default in the help", h)
+		}
+	})
+}
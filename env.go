@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/aryszka/config/keys"
+	"github.com/iancoleman/strcase"
+)
+
+// The environment variable names don't tell where the boundaries of the field names are, e.g. FOO_BAR_BAZ can
+// mean foo.bar-baz or foo-bar.baz. For this reason, the env nodes offer every possible prefix as a key, and
+// it's up to the target to pick the ones that it has fields for.
+
+type envVar struct {
+	key   []string
+	value string
+	unset bool
+}
+
+type envNode struct {
+	vars []envVar
+	typ  NodeType
+}
+
+// implemented by the nodes whose keys may not match the target, e.g. the env nodes offering every prefix
+type ambiguousNode interface {
+	ambiguous() bool
+}
+
+type envSource struct {
+	prefix  string
+	environ []string
+}
+
+func (n envNode) values() []string {
+	var v []string
+	for _, vi := range n.vars {
+		if len(vi.key) == 0 && !vi.unset {
+			v = append(v, vi.value)
+		}
+	}
+
+	return v
+}
+
+func (n envNode) Type() NodeType {
+	if n.typ != undefined {
+		return n.typ
+	}
+
+	var (
+		t     NodeType
+		unset bool
+	)
+
+	for _, vi := range n.vars {
+		switch {
+		case len(vi.key) > 0:
+			t |= Structure
+		case vi.unset:
+			unset = true
+		default:
+			t |= Primitive | List
+		}
+	}
+
+	if t == undefined && unset {
+		return Nil
+	}
+
+	if t == undefined {
+		return Structure
+	}
+
+	return t
+}
+
+func (n envNode) ambiguous() bool { return true }
+
+func ambiguousOf(n Node) bool {
+	if an, ok := n.(ambiguousNode); ok {
+		return an.ambiguous()
+	}
+
+	return false
+}
+
+func (n envNode) isUnset() bool { return n.typ == undefined && n.Type() == Nil }
+
+func (n envNode) Primitive() interface{} {
+	v := n.values()
+	return v[len(v)-1]
+}
+
+func (n envNode) Len() int { return len(n.values()) }
+
+func (n envNode) Item(i int) Node {
+	return envNode{
+		vars: []envVar{{value: n.values()[i]}},
+		typ:  Primitive,
+	}
+}
+
+func (n envNode) Keys() []string {
+	var k []string
+	found := make(map[string]bool)
+	for _, vi := range n.vars {
+		for i := 1; i <= len(vi.key); i++ {
+			key := keys.CanonicalSymbol(strings.Join(vi.key[:i], "_"))
+			if found[key] {
+				continue
+			}
+
+			found[key] = true
+			k = append(k, key)
+		}
+	}
+
+	return k
+}
+
+func (n envNode) Field(key string) Node {
+	var f envNode
+	for _, vi := range n.vars {
+		for i := 1; i <= len(vi.key); i++ {
+			if keys.CanonicalSymbol(strings.Join(vi.key[:i], "_")) == key {
+				f.vars = append(f.vars, envVar{key: vi.key[i:], value: vi.value, unset: vi.unset})
+			}
+		}
+	}
+
+	return f
+}
+
+func splitEnvName(name string) []string {
+	var key []string
+	for _, symbol := range strings.Split(name, "_") {
+		if symbol != "" {
+			key = append(key, symbol)
+		}
+	}
+
+	return key
+}
+
+func (s envSource) sourceName() string { return "env" }
+
+func (s envSource) Read() (Node, error) { return s.readTyped(nil, options{}) }
+
+func (s envSource) readTyped(_ reflect.Type, o options) (Node, error) {
+	var n envNode
+	for _, e := range s.environ {
+		eq := strings.Index(e, "=")
+		if eq <= 0 {
+			continue
+		}
+
+		name, value := e[:eq], e[eq+1:]
+		if !strings.HasPrefix(name, s.prefix) {
+			continue
+		}
+
+		key := splitEnvName(name[len(s.prefix):])
+		if len(key) == 0 {
+			continue
+		}
+
+		n.vars = append(n.vars, envVar{key: key, value: value, unset: o.unsetEmptyEnv && value == ""})
+	}
+
+	if len(n.vars) == 0 {
+		return nil, ErrNoConfig
+	}
+
+	return n, nil
+}
+
+// Env creates a source from environment variables in the form of os.Environ(). When environ is nil,
+// os.Environ() is used. Only the variables starting with the prefix are considered, e.g. with the prefix
+// myapp, MYAPP_SOURCE_KUBERNETES_ENABLED is mapped to source.kubernetes.enabled.
+func Env(prefix string, environ []string) Source {
+	if environ == nil {
+		environ = os.Environ()
+	}
+
+	if prefix != "" {
+		prefix = strcase.ToScreamingSnake(prefix) + "_"
+	}
+
+	e := make([]string, len(environ))
+	copy(e, environ)
+	return envSource{prefix: prefix, environ: e}
+}
@@ -0,0 +1,136 @@
+package config
+
+import "testing"
+
+func TestEnv(t *testing.T) {
+	t.Run("no matching variables", func(t *testing.T) {
+		o := struct{ Foo int }{42}
+		s := Env("myapp", []string{"FOO=21", "OTHERAPP_FOO=36"})
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != 42 {
+			t.Error("failed to ignore variables without prefix", o.Foo)
+		}
+	})
+
+	t.Run("nested keys", func(t *testing.T) {
+		var o struct {
+			Source struct {
+				Kubernetes struct {
+					Enabled bool
+					ApiURL  string
+				}
+			}
+		}
+
+		s := Env("myapp", []string{
+			"MYAPP_SOURCE_KUBERNETES_ENABLED=true",
+			"MYAPP_SOURCE_KUBERNETES_API_URL=https://kubernetes",
+		})
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if !o.Source.Kubernetes.Enabled || o.Source.Kubernetes.ApiURL != "https://kubernetes" {
+			t.Error("failed to apply nested keys", o)
+		}
+	})
+
+	t.Run("ambiguous boundaries", func(t *testing.T) {
+		var o struct {
+			Foo    struct{ BarBaz int }
+			FooBar struct{ Baz int }
+		}
+
+		s := Env("", []string{"FOO_BAR_BAZ=42"})
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo.BarBaz != 42 || o.FooBar.Baz != 42 {
+			t.Error("failed to apply ambiguous keys", o)
+		}
+	})
+
+	t.Run("multi-word prefix", func(t *testing.T) {
+		var o struct{ Foo int }
+		s := Env("my-app", []string{"MY_APP_FOO=42"})
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != 42 {
+			t.Error("failed to apply with multi-word prefix", o.Foo)
+		}
+	})
+
+	t.Run("override file", func(t *testing.T) {
+		var o struct{ Foo, Bar int }
+		s := Merge(
+			iniString("foo = 21\nbar = 36"),
+			Env("myapp", []string{"MYAPP_FOO=42"}),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != 42 || o.Bar != 36 {
+			t.Error("failed to override file with env", o)
+		}
+	})
+	t.Run("map", func(t *testing.T) {
+		var o struct{ Labels map[string]string }
+		s := Env("myapp", []string{"MYAPP_LABELS_FOO_BAR=1", "MYAPP_LABELS_BAZ=2"})
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Labels) != 2 || o.Labels["foo-bar"] != "1" || o.Labels["baz"] != "2" {
+			t.Error("failed to apply the map", o.Labels)
+		}
+	})
+
+	t.Run("map of structures", func(t *testing.T) {
+		var o struct{ Backends map[string]struct{ Port int } }
+		s := Env("myapp", []string{"MYAPP_BACKENDS_FOO_PORT=8080"})
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Backends) != 1 || o.Backends["foo"].Port != 8080 {
+			t.Error("failed to apply the map", o.Backends)
+		}
+	})
+
+	t.Run("map of structures with multi-word keys", func(t *testing.T) {
+		var o struct{ Backends map[string]struct{ Port int } }
+		s := Env("myapp", []string{"MYAPP_BACKENDS_API_SERVER_PORT=8080"})
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Backends) != 1 || o.Backends["api-server"].Port != 8080 {
+			t.Error("failed to apply the map", o.Backends)
+		}
+	})
+
+	t.Run("map merged with file", func(t *testing.T) {
+		var o struct{ Labels map[string]string }
+		s := Merge(
+			jsonString(`{"labels": {"foo": "bar"}}`),
+			Env("myapp", []string{"MYAPP_LABELS_FOO_BAR=1"}),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Labels) != 2 || o.Labels["foo"] != "bar" || o.Labels["foo-bar"] != "1" {
+			t.Error("failed to apply the map", o.Labels)
+		}
+	})
+}
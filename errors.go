@@ -0,0 +1,239 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Error is returned by Apply when a value can't be applied to the target, or when the target fails
+// validation. It matches the underlying error, e.g. ErrInvalidInputValue, with errors.Is.
+type Error struct {
+	// Key is the canonical path of the failing key, e.g. []string{"source", "kubernetes", "api-url"}. The
+	// list items are represented by their index.
+	Key []string
+
+	// Source is the name of the source that the failing value comes from, when known, e.g. the path of a
+	// file.
+	Source string
+
+	// Value is the offending primitive value, when there is one.
+	Value interface{}
+
+	// Line and Column tell the position of the value in the input, when known, e.g. in case of the INI
+	// files. They start from 1.
+	Line, Column int
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Errors is returned by Apply with the AllErrors option, when applying the config failed. It contains the
+// failures ordered by their key path, and matches any of them with errors.Is and errors.As.
+type Errors []*Error
+
+// the origin of a node, when known
+type origin struct {
+	source       string
+	line, column int
+}
+
+// implemented by the nodes that know where their values come from
+type originNode interface {
+	origin() origin
+}
+
+// carries the name of the source to the nodes read from it
+type namedNode struct {
+	Node
+	name string
+}
+
+func (e *Error) Error() string {
+	var s []string
+	if e.Source != "" {
+		s = append(s, fmt.Sprintf("source=%s", e.Source))
+	}
+
+	if e.Line > 0 {
+		s = append(s, fmt.Sprintf("line=%d", e.Line), fmt.Sprintf("column=%d", e.Column))
+	}
+
+	if len(e.Key) > 0 {
+		s = append(s, fmt.Sprintf("key=%s", strings.Join(e.Key, ".")))
+	}
+
+	if e.Value != nil {
+		s = append(s, fmt.Sprintf("value=%v", e.Value))
+	}
+
+	s = append(s, e.Err.Error())
+	return strings.Join(s, "; ")
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+func (e Errors) Error() string {
+	var s []string
+	for _, ei := range e {
+		s = append(s, ei.Error())
+	}
+
+	return strings.Join(s, "\n")
+}
+
+func (e Errors) Is(target error) bool {
+	for _, ei := range e {
+		if errors.Is(ei, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e Errors) As(target interface{}) bool {
+	for _, ei := range e {
+		if errors.As(ei, target) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func originOf(n Node) origin {
+	if on, ok := n.(originNode); ok {
+		return on.origin()
+	}
+
+	return origin{}
+}
+
+func withSourceName(n Node, name string) Node {
+	if n == nil || name == "" {
+		return n
+	}
+
+	return namedNode{Node: n, name: name}
+}
+
+func (n namedNode) Item(i int) Node       { return namedNode{Node: n.Node.Item(i), name: n.name} }
+func (n namedNode) Field(key string) Node { return namedNode{Node: n.Node.Field(key), name: n.name} }
+
+func (n namedNode) failure() error  { return failureOf(n.Node) }
+func (n namedNode) isUnset() bool   { return unsetOf(n.Node) }
+func (n namedNode) ambiguous() bool { return ambiguousOf(n.Node) }
+
+func (n namedNode) withStrategy(s MergeStrategy) Node {
+	return namedNode{Node: withMergeStrategy(n.Node, s), name: n.name}
+}
+
+func (n namedNode) forKind(k reflect.Kind) Node {
+	return namedNode{Node: mergedFor(n.Node, k), name: n.name}
+}
+
+func (n namedNode) origin() origin {
+	o := originOf(n.Node)
+	if o.source == "" {
+		o.source = n.name
+	}
+
+	return o
+}
+
+// the primitive value of a node, when it has one
+func primitiveOf(n Node) interface{} {
+	t := n.Type()
+	if t == Nil || t&Primitive == 0 || t&List != 0 && n.Len() == 0 {
+		return nil
+	}
+
+	return n.Primitive()
+}
+
+// creates an error at the failing node, unless it was already created deeper in the tree
+func errorAt(n Node, err error) error {
+	switch err.(type) {
+	case *Error, Errors:
+		return err
+	}
+
+	o := originOf(n)
+	return &Error{
+		Source: o.source,
+		Value:  primitiveOf(n),
+		Line:   o.line,
+		Column: o.column,
+		Err:    err,
+	}
+}
+
+// prepends a key to the path of an error while it is returned from the nested fields
+func errorIn(key string, err error) error {
+	switch e := err.(type) {
+	case *Error:
+		e.Key = append([]string{key}, e.Key...)
+		return e
+	case Errors:
+		for _, ei := range e {
+			ei.Key = append([]string{key}, ei.Key...)
+		}
+
+		return e
+	default:
+		return &Error{Key: []string{key}, Err: err}
+	}
+}
+
+// flattens the collected errors, or returns nil if there were none
+func joinErrors(errs []error) error {
+	var e Errors
+	for _, err := range errs {
+		switch ei := err.(type) {
+		case nil:
+		case *Error:
+			e = append(e, ei)
+		case Errors:
+			e = append(e, ei...)
+		default:
+			e = append(e, &Error{Err: err})
+		}
+	}
+
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// compares the key paths by their symbols, and the list indexes by their numeric value
+func keyLess(a, b []string) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+
+		ia, erra := strconv.Atoi(a[i])
+		ib, errb := strconv.Atoi(b[i])
+		if erra == nil && errb == nil {
+			return ia < ib
+		}
+
+		return a[i] < b[i]
+	}
+
+	return len(a) < len(b)
+}
+
+func sortErrors(err error) error {
+	if e, ok := err.(Errors); ok {
+		sort.SliceStable(e, func(i, j int) bool { return keyLess(e[i].Key, e[j].Key) })
+	}
+
+	return err
+}
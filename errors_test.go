@@ -0,0 +1,160 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestError(t *testing.T) {
+	t.Run("key path and value", func(t *testing.T) {
+		var o struct {
+			Foo struct {
+				BarBaz []int
+			}
+		}
+
+		err := Apply(&o, jsonString(`{"foo": {"bar-baz": [1, "two"]}}`))
+		if !errors.Is(err, ErrInvalidInputValue) {
+			t.Fatal("failed to fail with the right error", err)
+		}
+
+		var e *Error
+		if !errors.As(err, &e) {
+			t.Fatal("failed to return a structured error", err)
+		}
+
+		if strings.Join(e.Key, ".") != "foo.bar-baz.1" || e.Value != "two" {
+			t.Error("invalid error details", e)
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		var o struct{ Foo int8 }
+		err := Apply(&o, jsonString(`{"foo": 300}`))
+		if !errors.Is(err, ErrNumericOverflow) {
+			t.Fatal("failed to fail with the right error", err)
+		}
+
+		var e *Error
+		if !errors.As(err, &e) || strings.Join(e.Key, ".") != "foo" {
+			t.Error("invalid error details", err)
+		}
+	})
+
+	t.Run("ini position and source name", func(t *testing.T) {
+		withTestFiles(t, map[string]string{
+			"config.ini": "foo = 42\n\n[bar]\nbaz =  qux\n",
+		}, func(dir string) {
+			var o struct {
+				Foo int
+				Bar struct{ Baz int }
+			}
+
+			name := filepath.Join(dir, "config.ini")
+			err := Apply(&o, Merge(File(name), Env("myapp", []string{"MYAPP_FOO=36"})))
+			if !errors.Is(err, ErrInvalidInputValue) {
+				t.Fatal("failed to fail with the right error", err)
+			}
+
+			var e *Error
+			if !errors.As(err, &e) {
+				t.Fatal("failed to return a structured error", err)
+			}
+
+			if e.Source != name ||
+				e.Line != 4 ||
+				e.Column != 8 ||
+				strings.Join(e.Key, ".") != "bar.baz" ||
+				e.Value != "qux" {
+				t.Error("invalid error details", e)
+			}
+
+			expected := "source=" + name + "; line=4; column=8; key=bar.baz; value=qux; "
+			if !strings.HasPrefix(e.Error(), expected) {
+				t.Error("invalid error message", e)
+			}
+		})
+	})
+
+	t.Run("merged source name", func(t *testing.T) {
+		var o struct{ Foo, Bar int }
+		err := Apply(&o, Merge(
+			jsonString(`{"foo": 42, "bar": 36}`),
+			Env("myapp", []string{"MYAPP_BAR=baz"}),
+		))
+
+		var e *Error
+		if !errors.As(err, &e) || e.Source != "env" || strings.Join(e.Key, ".") != "bar" {
+			t.Error("invalid error details", err)
+		}
+	})
+
+	t.Run("all errors", func(t *testing.T) {
+		type target struct {
+			Foo  int
+			Bar  []int8
+			Baz  map[string]bool
+			Qux  string `oneof:"a,b"`
+			Quux struct {
+				FooBar int `required:"true"`
+			}
+		}
+
+		s := jsonString(`{
+			"foo": "one",
+			"bar": [1, 300, 2, "x", 3, 4, 5, 6, 7, 8, 9, 1000],
+			"baz": {"b": "yes", "a": "no", "c": true},
+			"qux": "c",
+			"quux": {"Foo-Bar": 1, "foo_bar": 2}
+		}`)
+
+		var o target
+		err := Apply(&o, s, AllErrors())
+
+		var e Errors
+		if !errors.As(err, &e) {
+			t.Fatal("failed to return multiple errors", err)
+		}
+
+		var k []string
+		for _, ei := range e {
+			k = append(k, strings.Join(ei.Key, "."))
+		}
+
+		expected := "bar.1, bar.3, bar.11, baz.a, baz.b, foo, quux.foo-bar, quux.foo-bar, qux"
+		if strings.Join(k, ", ") != expected {
+			t.Error("failed to collect all errors in key order", strings.Join(k, ", "))
+		}
+
+		if !strings.Contains(err.Error(), "key=quux.foo-bar; conflicting keys: Foo-Bar, foo_bar") {
+			t.Error("failed to report the conflicting keys", err)
+		}
+
+		if !errors.Is(err, ErrNumericOverflow) ||
+			!errors.Is(err, ErrConflictingKeys) ||
+			!errors.Is(err, ErrValidationFailed) {
+			t.Error("failed to match the collected errors", err)
+		}
+
+		if o.Bar[2] != 2 || !o.Baz["c"] {
+			t.Error("failed to apply the valid values", o)
+		}
+	})
+
+	t.Run("all errors, single", func(t *testing.T) {
+		var o struct{ Foo int }
+		var e Errors
+		if err := Apply(&o, jsonString(`{"foo": "bar"}`), AllErrors()); !errors.As(err, &e) || len(e) != 1 {
+			t.Error("failed to return multiple errors", err)
+		}
+	})
+
+	t.Run("all errors, valid", func(t *testing.T) {
+		var o struct{ Foo int }
+		if err := Apply(&o, jsonString(`{"foo": 42}`), AllErrors()); err != nil {
+			t.Error(err)
+		}
+	})
+}
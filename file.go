@@ -0,0 +1,135 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type fileSource struct {
+	name     string
+	readFile func(string) ([]byte, error)
+	cache    readCache
+}
+
+var ErrUnknownFileFormat = errors.New("unknown file format")
+
+var (
+	fileFormatsMx sync.RWMutex
+	fileFormats   = map[string]func(io.Reader) Source{
+		"":      INI,
+		".ini":  INI,
+		".json": JSON,
+		".yml":  YAML,
+		".yaml": YAML,
+		".toml": TOML,
+	}
+)
+
+func unknownFileFormat(ext string) error {
+	return fmt.Errorf("%w: %s", ErrUnknownFileFormat, ext)
+}
+
+// RegisterFileFormat sets the source used for the files with the extension ext, e.g. ".conf". The empty
+// extension is used for files without an extension, and by default it is INI.
+func RegisterFileFormat(ext string, format func(io.Reader) Source) {
+	fileFormatsMx.Lock()
+	defer fileFormatsMx.Unlock()
+	fileFormats[strings.ToLower(ext)] = format
+}
+
+func fileExt(path string) string {
+	ext := filepath.Ext(path)
+	if ext == filepath.Base(path) {
+		// dot files, e.g. .config
+		return ""
+	}
+
+	return strings.ToLower(ext)
+}
+
+// FileFormat returns the source registered for the extension of the path.
+func FileFormat(path string) (func(io.Reader) Source, error) {
+	fileFormatsMx.RLock()
+	defer fileFormatsMx.RUnlock()
+	ext := fileExt(path)
+	format, ok := fileFormats[ext]
+	if !ok {
+		return nil, unknownFileFormat(ext)
+	}
+
+	return format, nil
+}
+
+func (s *fileSource) sourceName() string { return s.name }
+func (s *fileSource) files() []string    { return []string{s.name} }
+
+func (s *fileSource) reset() { s.cache.reset() }
+
+func (s *fileSource) sourceError(err error) error {
+	return namedSourceErrorf(s.name, "%w", err)
+}
+
+func (s *fileSource) open() (io.ReadCloser, error) {
+	if s.readFile == nil {
+		return os.Open(s.name)
+	}
+
+	b, err := s.readFile(s.name)
+	if err != nil {
+		return nil, err
+	}
+
+	return ioutil.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (s *fileSource) read() (Node, error) {
+	f, err := s.open()
+	if os.IsNotExist(err) {
+		return nil, ErrNoConfig
+	}
+
+	if err != nil {
+		return nil, s.sourceError(err)
+	}
+
+	defer f.Close()
+	format, err := FileFormat(s.name)
+	if err != nil {
+		return nil, s.sourceError(err)
+	}
+
+	n, err := format(f).Read()
+	if errors.Is(err, ErrNoConfig) {
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, s.sourceError(err)
+	}
+
+	return n, nil
+}
+
+func (s *fileSource) Read() (Node, error) {
+	return s.cache.read(s.read)
+}
+
+// File creates a source from the file at path, selecting the format by its extension. When the file doesn't
+// exist, reading the source fails with ErrNoConfig.
+func File(path string) Source {
+	return &fileSource{name: path}
+}
+
+// FileWith creates a file source like File, but it reads the content of the file with the read function, e.g.
+// from an embedded or a test file system. When read fails with os.ErrNotExist, reading the source fails with
+// ErrNoConfig.
+func FileWith(path string, read func(string) ([]byte, error)) Source {
+	return &fileSource{name: path, readFile: read}
+}
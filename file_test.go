@@ -0,0 +1,129 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func withTestFiles(t *testing.T, files map[string]string, test func(dir string)) {
+	dir, err := ioutil.TempDir("", "config-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	test(dir)
+}
+
+func TestFile(t *testing.T) {
+	t.Run("by extension", func(t *testing.T) {
+		withTestFiles(t, map[string]string{
+			"config.json": `{"foo": 1}`,
+			"config.yaml": "bar: 2",
+			"config.yml":  "baz: 3",
+			"config.ini":  "qux = 4",
+			"config":      "quux = 5",
+		}, func(dir string) {
+			var o struct{ Foo, Bar, Baz, Qux, Quux int }
+			s := Merge(
+				File(filepath.Join(dir, "config.json")),
+				File(filepath.Join(dir, "config.yaml")),
+				File(filepath.Join(dir, "config.yml")),
+				File(filepath.Join(dir, "config.ini")),
+				File(filepath.Join(dir, "config")),
+			)
+
+			if err := Apply(&o, s); err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Foo != 1 || o.Bar != 2 || o.Baz != 3 || o.Qux != 4 || o.Quux != 5 {
+				t.Error("failed to apply files", o)
+			}
+		})
+	})
+
+	t.Run("missing", func(t *testing.T) {
+		withTestFiles(t, map[string]string{"config.ini": "foo = 42"}, func(dir string) {
+			var o struct{ Foo int }
+			s := Override(
+				File(filepath.Join(dir, "config.ini")),
+				File(filepath.Join(dir, "missing.json")),
+				File(filepath.Join(dir, "missing.conf")),
+			)
+
+			if err := Apply(&o, s); err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Foo != 42 {
+				t.Error("failed to skip missing file", o.Foo)
+			}
+		})
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		withTestFiles(t, map[string]string{"config.foo": "foo = 42"}, func(dir string) {
+			var o struct{ Foo int }
+			if err := Apply(&o, File(filepath.Join(dir, "config.foo"))); !errors.Is(err, ErrUnknownFileFormat) {
+				t.Error("failed to fail with the right error", err)
+			}
+		})
+	})
+
+	t.Run("registered format", func(t *testing.T) {
+		RegisterFileFormat(".conf", INI)
+		defer func() {
+			fileFormatsMx.Lock()
+			defer fileFormatsMx.Unlock()
+			delete(fileFormats, ".conf")
+		}()
+
+		withTestFiles(t, map[string]string{"config.conf": "foo = 42"}, func(dir string) {
+			var o struct{ Foo int }
+			if err := Apply(&o, File(filepath.Join(dir, "config.conf"))); err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Foo != 42 {
+				t.Error("failed to apply registered format", o.Foo)
+			}
+		})
+	})
+
+	t.Run("source name in error", func(t *testing.T) {
+		withTestFiles(t, map[string]string{"config.json": "{"}, func(dir string) {
+			var o struct{ Foo int }
+			name := filepath.Join(dir, "config.json")
+			err := Apply(&o, File(name))
+			if err == nil || !strings.Contains(err.Error(), "source="+name) {
+				t.Error("failed to fail with the source name", err)
+			}
+		})
+	})
+	t.Run("custom read", func(t *testing.T) {
+		read := func(name string) ([]byte, error) {
+			if name != "/etc/app.json" {
+				return nil, os.ErrNotExist
+			}
+
+			return []byte(`{"foo": "bar"}`), nil
+		}
+
+		var o struct{ Foo int }
+		err := Apply(&o, Merge(FileWith("/etc/app.json", read), FileWith("/etc/missing.json", read)))
+		if !errors.Is(err, ErrInvalidInputValue) || !strings.Contains(err.Error(), "source=/etc/app.json") {
+			t.Error("failed to fail with the source name", err)
+		}
+	})
+}
@@ -0,0 +1,404 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/aryszka/config/ini"
+	"github.com/aryszka/config/keys"
+)
+
+// FlagsMode controls which flag forms are accepted by the flags source.
+type FlagsMode int
+
+const (
+	FlagsDefault FlagsMode = 0
+	SingleDash   FlagsMode = 1 << iota
+	BanShort
+	BanGroupedShort
+	PositionalLast
+)
+
+type flag struct {
+	name, value string
+	key         []string
+	hasValue    bool
+	unset       bool
+}
+
+type flagArity int
+
+const (
+	singleValue flagArity = iota
+	noValue
+	multipleValues
+)
+
+type flagsSource struct {
+	args  []string
+	mode  FlagsMode
+	cache readCache
+}
+
+var ErrInvalidFlag = errors.New("invalid flag")
+
+func flagAfterPositional(name string) error {
+	return fmt.Errorf("%w: flag after positional argument not allowed: %s", ErrInvalidFlag, name)
+}
+
+func doubleDashNotAllowed(name string) error {
+	return fmt.Errorf("%w: double dash not allowed: %s", ErrInvalidFlag, name)
+}
+
+func shortNotAllowed(name string) error {
+	return fmt.Errorf("%w: short flag not allowed: %s", ErrInvalidFlag, name)
+}
+
+func groupedShortNotAllowed(name string) error {
+	return fmt.Errorf("%w: flag grouping not allowed: %s", ErrInvalidFlag, name)
+}
+
+func invalidFlagKey(name string) error {
+	return fmt.Errorf("%w: invalid key: %s", ErrInvalidFlag, name)
+}
+
+func isFlag(arg string) bool {
+	return strings.HasPrefix(arg, "-") && len(arg) > 1 && arg != "--"
+}
+
+func splitFlagValue(arg string) flag {
+	eq := strings.Index(arg, "=")
+	if eq < 0 {
+		return flag{name: arg}
+	}
+
+	return flag{name: arg[:eq], value: arg[eq+1:], hasValue: true}
+}
+
+// the type of the target field decides whether a flag takes the following argument as its value. Without
+// a known type, a flag takes a single value when it's followed by a non-flag argument.
+func targetType(t reflect.Type, o options, key []string) (reflect.Type, bool) {
+	if t == nil {
+		return nil, false
+	}
+
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if len(key) == 0 {
+		return t, true
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		f, ok := fieldsByKey(t, o)[keys.CanonicalSymbol(key[0])]
+		if !ok {
+			return nil, false
+		}
+
+		return targetType(f.Type, o, key[1:])
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, false
+		}
+
+		return targetType(t.Elem(), o, key[1:])
+	default:
+		return nil, false
+	}
+}
+
+func lastFlagKey(m FlagsMode, name string) []string {
+	if strings.HasPrefix(name, "--") || m&SingleDash != 0 {
+		return strings.Split(strings.TrimLeft(name, "-"), ".")
+	}
+
+	return []string{name[len(name)-1:]}
+}
+
+// -h and --help are reserved for requesting help, unless the target has a field for them
+func isHelpFlag(t reflect.Type, o options, key []string) bool {
+	if len(key) != 1 || key[0] != "h" && key[0] != "help" {
+		return false
+	}
+
+	_, isField := targetType(t, o, key)
+	return !isField
+}
+
+// --no-foo unsets foo, and --no-foo.bar unsets foo.bar, unless the target has a field for the key with the
+// prefix. When the target is known, it needs to have a field for the key without the prefix.
+func isUnsetFlag(t reflect.Type, o options, key []string) bool {
+	if !strings.HasPrefix(key[0], "no-") || len(key[0]) == len("no-") {
+		return false
+	}
+
+	if _, isField := targetType(t, o, key); isField {
+		return false
+	}
+
+	if t == nil {
+		return true
+	}
+
+	_, isField := targetType(t, o, unsetFlagKey(key))
+	return isField
+}
+
+func unsetFlagKey(key []string) []string {
+	return append([]string{key[0][len("no-"):]}, key[1:]...)
+}
+
+func arityOf(m FlagsMode, t reflect.Type, o options, name string) flagArity {
+	key := lastFlagKey(m, name)
+	if isHelpFlag(t, o, key) || isUnsetFlag(t, o, key) {
+		return noValue
+	}
+
+	ft, ok := targetType(t, o, key)
+	if !ok || isCustomDecoded(ft) {
+		return singleValue
+	}
+
+	switch ft.Kind() {
+	case reflect.Bool:
+		return noValue
+	case reflect.Slice:
+		et := ft.Elem()
+		for et.Kind() == reflect.Ptr {
+			et = et.Elem()
+		}
+
+		switch et.Kind() {
+		case reflect.Slice, reflect.Struct, reflect.Map:
+			return singleValue
+		default:
+			return multipleValues
+		}
+	default:
+		return singleValue
+	}
+}
+
+func groupFlags(m FlagsMode, t reflect.Type, o options, args []string) (f []flag, p []string, err error) {
+	positionalLast := m&PositionalLast != 0
+	var hasPositional bool
+	for i := 0; i < len(args); i++ {
+		current := args[i]
+		if current == "--" {
+			p = append(p, args[i+1:]...)
+			return
+		}
+
+		if !isFlag(current) {
+			p = append(p, current)
+			hasPositional = true
+			continue
+		}
+
+		if positionalLast && hasPositional {
+			err = flagAfterPositional(current)
+			return
+		}
+
+		fi := splitFlagValue(current)
+		if fi.hasValue {
+			f = append(f, fi)
+			continue
+		}
+
+		arity := arityOf(m, t, o, fi.name)
+		if arity == noValue || i == len(args)-1 || isFlag(args[i+1]) || args[i+1] == "--" {
+			f = append(f, fi)
+			continue
+		}
+
+		for i < len(args)-1 && !isFlag(args[i+1]) && args[i+1] != "--" {
+			fi.value = args[i+1]
+			fi.hasValue = true
+			f = append(f, fi)
+			i++
+
+			if arity != multipleValues {
+				break
+			}
+		}
+	}
+
+	return
+}
+
+func splitFlagKey(name string) ([]string, error) {
+	key := strings.Split(name, ".")
+	for _, symbol := range key {
+		if symbol == "" {
+			return nil, invalidFlagKey(name)
+		}
+	}
+
+	return key, nil
+}
+
+func processKeys(m FlagsMode, f []flag) ([]flag, error) {
+	var r []flag
+	singleDashMode := m&SingleDash != 0
+	banShort := m&BanShort != 0
+	banGroupedShort := m&BanGroupedShort != 0
+	for _, fi := range f {
+		hasDoubleDash := strings.HasPrefix(fi.name, "--")
+		if hasDoubleDash && singleDashMode {
+			return nil, doubleDashNotAllowed(fi.name)
+		}
+
+		if hasDoubleDash || singleDashMode {
+			key, err := splitFlagKey(strings.TrimLeft(fi.name, "-"))
+			if err != nil {
+				return nil, err
+			}
+
+			fi.key = key
+			r = append(r, fi)
+			continue
+		}
+
+		if banShort {
+			return nil, shortNotAllowed(fi.name)
+		}
+
+		if banGroupedShort && len(fi.name) > 2 {
+			return nil, groupedShortNotAllowed(fi.name)
+		}
+
+		for _, symbol := range strings.Split(fi.name[1:len(fi.name)-1], "") {
+			r = append(r, flag{name: fi.name, key: []string{symbol}})
+		}
+
+		fi.key = []string{fi.name[len(fi.name)-1:]}
+		r = append(r, fi)
+	}
+
+	return r, nil
+}
+
+func addFlag(n *ini.Node, f flag) {
+	if len(f.key) == 0 && f.unset {
+		n.Values, n.Fields = nil, nil
+		n.Unset = true
+		return
+	}
+
+	if len(f.key) == 0 {
+		v := f.value
+		if !f.hasValue {
+			v = "true"
+		}
+
+		n.Values = append(n.Values, v)
+		return
+	}
+
+	if n.Fields == nil {
+		n.Fields = make(map[string]*ini.Node)
+	}
+
+	child, ok := n.Fields[f.key[0]]
+	if !ok {
+		child = &ini.Node{}
+		n.Fields[f.key[0]] = child
+	}
+
+	f.key = f.key[1:]
+	addFlag(child, f)
+}
+
+func buildFlags(f []flag, p []string) Node {
+	n := &ini.Node{}
+	for _, fi := range f {
+		addFlag(n, fi)
+	}
+
+	n.Values = append(n.Values, p...)
+	return iniNode{ini: n}
+}
+
+func processFlags(
+	m FlagsMode,
+	t reflect.Type,
+	o options,
+	args []string,
+) (f []flag, p []string, help bool, err error) {
+	if f, p, err = groupFlags(m, t, o, args); err != nil {
+		return
+	}
+
+	var all []flag
+	if all, err = processKeys(m, f); err != nil {
+		return
+	}
+
+	f = nil
+	for _, fi := range all {
+		if isHelpFlag(t, o, fi.key) {
+			help = true
+			continue
+		}
+
+		if !fi.hasValue && isUnsetFlag(t, o, fi.key) {
+			fi.key = unsetFlagKey(fi.key)
+			fi.unset = true
+		}
+
+		f = append(f, fi)
+	}
+
+	return
+}
+
+func parseFlags(m FlagsMode, t reflect.Type, o options, args []string) (Node, error) {
+	f, p, _, err := processFlags(m, t, o, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildFlags(f, p), nil
+}
+
+func (s *flagsSource) Read() (Node, error) {
+	return s.cache.read(func() (Node, error) {
+		return parseFlags(s.mode, nil, options{}, s.args)
+	})
+}
+
+func (s *flagsSource) readTyped(t reflect.Type, o options) (Node, error) {
+	if t == nil {
+		return s.Read()
+	}
+
+	return parseFlags(s.mode, t, o, s.args)
+}
+
+func (s *flagsSource) sourceName() string { return "flags" }
+
+func (s *flagsSource) positional(t reflect.Type, o options) ([]string, error) {
+	_, p, _, err := processFlags(s.mode, t, o, s.args)
+	return p, err
+}
+
+func (s *flagsSource) helpRequested(t reflect.Type, o options) bool {
+	_, _, help, err := processFlags(s.mode, t, o, s.args)
+	return help && err == nil
+}
+
+// Flags creates a source from command line arguments, typically os.Args[1:]. The positional arguments are
+// stored as the values of the root node. When applied, the flags are parsed based on the type of the target,
+// so that bool flags don't take the following argument, while list flags take all the following non-flag
+// arguments. The -h and --help flags are not applied, unless the target has a field for them, and they can
+// be checked with HelpRequested. The --no-foo flags unset foo, unless the target has a field for no-foo.
+func Flags(args []string, mode FlagsMode) Source {
+	a := make([]string, len(args))
+	copy(a, args)
+	return &flagsSource{args: a, mode: mode}
+}
@@ -0,0 +1,283 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFlags(t *testing.T) {
+	t.Run("long flags", func(t *testing.T) {
+		var o struct {
+			Foo struct{ Bar struct{ Baz int } }
+			Qux string
+		}
+
+		s := Flags([]string{"--foo.bar.baz=42", "--qux", "quux"}, FlagsDefault)
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo.Bar.Baz != 42 || o.Qux != "quux" {
+			t.Error("failed to apply flags", o)
+		}
+	})
+
+	t.Run("no value", func(t *testing.T) {
+		var o struct{ Foo, Bar bool }
+		s := Flags([]string{"--foo", "--bar"}, FlagsDefault)
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if !o.Foo || !o.Bar {
+			t.Error("failed to apply flags without value")
+		}
+	})
+
+	t.Run("repeated flags", func(t *testing.T) {
+		var o struct{ Foo []int }
+		s := Flags([]string{"--foo", "1", "--foo", "2", "--foo=3"}, FlagsDefault)
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Foo) != 3 || o.Foo[0] != 1 || o.Foo[1] != 2 || o.Foo[2] != 3 {
+			t.Error("failed to apply repeated flags", o.Foo)
+		}
+	})
+
+	t.Run("positional", func(t *testing.T) {
+		var o []string
+		s := Flags([]string{"foo", "--bar", "baz", "qux", "--", "--quux"}, FlagsDefault)
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o) != 3 || o[0] != "foo" || o[1] != "qux" || o[2] != "--quux" {
+			t.Error("failed to collect positional arguments", o)
+		}
+	})
+
+	t.Run("grouped short flags", func(t *testing.T) {
+		var o struct {
+			A, B bool
+			C    int
+		}
+
+		s := Flags([]string{"-abc", "42"}, FlagsDefault)
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if !o.A || !o.B || o.C != 42 {
+			t.Error("failed to apply grouped short flags", o)
+		}
+	})
+
+	t.Run("single dash", func(t *testing.T) {
+		var o struct{ Foo int }
+		s := Flags([]string{"-foo", "42"}, SingleDash)
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != 42 {
+			t.Error("failed to apply single dash flag", o.Foo)
+		}
+	})
+
+	t.Run("mode violations", func(t *testing.T) {
+		for _, test := range []struct {
+			title string
+			args  []string
+			mode  FlagsMode
+		}{{
+			title: "double dash in single dash mode",
+			args:  []string{"--foo"},
+			mode:  SingleDash,
+		}, {
+			title: "banned short",
+			args:  []string{"-f"},
+			mode:  BanShort,
+		}, {
+			title: "banned grouped short",
+			args:  []string{"-fo"},
+			mode:  BanGroupedShort,
+		}, {
+			title: "flag after positional",
+			args:  []string{"foo", "--bar"},
+			mode:  PositionalLast,
+		}, {
+			title: "empty key symbol",
+			args:  []string{"--foo..bar"},
+		}} {
+			t.Run(test.title, func(t *testing.T) {
+				var o struct{ Foo int }
+				if err := Apply(&o, Flags(test.args, test.mode)); !errors.Is(err, ErrInvalidFlag) {
+					t.Error("failed to fail with the right error", err)
+				}
+			})
+		}
+	})
+
+	t.Run("merge with file", func(t *testing.T) {
+		var o struct{ Foo, Bar int }
+		s := Merge(
+			jsonString(`{"foo": 21, "bar": 36}`),
+			Flags([]string{"--foo", "42"}, FlagsDefault),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != 42 || o.Bar != 36 {
+			t.Error("failed to merge flags", o)
+		}
+	})
+
+	t.Run("typed", func(t *testing.T) {
+		t.Run("bool flag followed by positional", func(t *testing.T) {
+			var o struct {
+				Verbose bool
+				Port    int
+				Files   []string
+			}
+
+			s := Flags([]string{"--verbose", "file.txt", "--port", "8080"}, FlagsDefault)
+			if err := Apply(&o, s); err != nil {
+				t.Fatal(err)
+			}
+
+			if !o.Verbose || o.Port != 8080 || len(o.Files) != 0 {
+				t.Error("failed to apply typed flags", o)
+			}
+		})
+
+		t.Run("explicit bool value", func(t *testing.T) {
+			o := struct{ Verbose bool }{true}
+			s := Flags([]string{"--verbose=false", "file.txt"}, FlagsDefault)
+			if err := Apply(&o, s); err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Verbose {
+				t.Error("failed to apply explicit bool value")
+			}
+		})
+
+		t.Run("grouped short bool", func(t *testing.T) {
+			var o struct{ A, B bool }
+			s := Flags([]string{"-ab", "file.txt"}, FlagsDefault)
+			if err := Apply(&o, s); err != nil {
+				t.Fatal(err)
+			}
+
+			if !o.A || !o.B {
+				t.Error("failed to apply grouped short bool flags", o)
+			}
+		})
+
+		t.Run("list", func(t *testing.T) {
+			var o struct {
+				Foo []int
+				Bar *bool
+			}
+
+			s := Flags([]string{"--foo", "1", "2", "--bar", "3"}, FlagsDefault)
+			if err := Apply(&o, s); err != nil {
+				t.Fatal(err)
+			}
+
+			if len(o.Foo) != 2 || o.Foo[0] != 1 || o.Foo[1] != 2 || o.Bar == nil || !*o.Bar {
+				t.Error("failed to apply list flag", o)
+			}
+		})
+
+		t.Run("nested", func(t *testing.T) {
+			var o struct {
+				Foo struct{ Bar map[string]bool }
+				Baz string
+			}
+
+			s := Merge(
+				jsonString(`{"baz": "qux"}`),
+				Flags([]string{"--foo.bar.baz", "--baz", "quux"}, FlagsDefault),
+			)
+
+			if err := Apply(&o, s); err != nil {
+				t.Fatal(err)
+			}
+
+			if !o.Foo.Bar["baz"] || o.Baz != "quux" {
+				t.Error("failed to apply nested typed flags", o)
+			}
+		})
+	})
+
+	t.Run("positional", func(t *testing.T) {
+		t.Run("typed", func(t *testing.T) {
+			var o struct {
+				Verbose bool
+				Port    int
+			}
+
+			s := Flags([]string{"--verbose", "foo", "--port", "8080", "bar", "--", "--baz"}, FlagsDefault)
+			p, err := ApplyWithPositional(&o, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !o.Verbose || o.Port != 8080 {
+				t.Error("failed to apply flags", o)
+			}
+
+			if len(p) != 3 || p[0] != "foo" || p[1] != "bar" || p[2] != "--baz" {
+				t.Error("failed to return positional arguments", p)
+			}
+		})
+
+		t.Run("merged", func(t *testing.T) {
+			var o struct{ Foo int }
+			s := Merge(
+				jsonString(`{"foo": 21}`),
+				Env("myapp", []string{"MYAPP_FOO=36"}),
+				Flags([]string{"--foo", "42", "bar"}, FlagsDefault),
+			)
+
+			p, err := ApplyWithPositional(&o, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Foo != 42 || len(p) != 1 || p[0] != "bar" {
+				t.Error("failed to return positional arguments", o, p)
+			}
+		})
+
+		t.Run("overridden", func(t *testing.T) {
+			var o struct{ Foo int }
+			s := Override(
+				Flags([]string{"--foo", "42", "bar"}, FlagsDefault),
+				jsonString(`{"foo": 21}`),
+			)
+
+			p, err := ApplyWithPositional(&o, s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Foo != 21 || len(p) != 0 {
+				t.Error("failed to ignore overridden positional arguments", o, p)
+			}
+		})
+
+		t.Run("fails", func(t *testing.T) {
+			var o struct{ Foo int }
+			s := Flags([]string{"foo", "--foo", "42"}, PositionalLast)
+			if _, err := ApplyWithPositional(&o, s); !errors.Is(err, ErrInvalidFlag) {
+				t.Error("failed to fail with the right error", err)
+			}
+		})
+	})
+}
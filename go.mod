@@ -3,6 +3,7 @@ module github.com/aryszka/config
 go 1.13
 
 require (
+	github.com/BurntSushi/toml v1.2.1
 	github.com/go-yaml/yaml v2.1.0+incompatible
 	github.com/iancoleman/strcase v0.0.0-20190422225806-e506e3ef7365
 )
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// Field describes a key accepted by a target.
+type Field struct {
+	Key         []string
+	Type        string
+	Default     string
+	Description string
+}
+
+// implemented by sources that can tell whether help was requested, e.g. the flags
+type helpSource interface {
+	helpRequested(reflect.Type, options) bool
+}
+
+const mapKeyPlaceholder = "<key>"
+
+func typeName(t reflect.Type) string {
+	switch t {
+	case durationType:
+		return "duration"
+	case timeType:
+		return "time"
+	}
+
+	if isCustomDecoded(t) {
+		return t.String()
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeName(t.Elem())
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64:
+		return "int"
+	case reflect.Uint,
+		reflect.Uint8,
+		reflect.Uint16,
+		reflect.Uint32,
+		reflect.Uint64:
+		return "uint"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.String:
+		return "string"
+	case reflect.Slice:
+		return "[]" + typeName(t.Elem())
+	case reflect.Map:
+		return "map[string]" + typeName(t.Elem())
+	case reflect.Interface:
+		return "any"
+	default:
+		return t.Kind().String()
+	}
+}
+
+func defaultString(v reflect.Value) string {
+	if !v.IsValid() || v.IsZero() {
+		return ""
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+func collectFields(
+	fields []Field,
+	key []string,
+	t reflect.Type,
+	v reflect.Value,
+	tag reflect.StructTag,
+	o options,
+	visiting map[reflect.Type]bool,
+) []Field {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+		if v.IsValid() && !v.IsNil() {
+			v = v.Elem()
+		} else {
+			v = reflect.Value{}
+		}
+	}
+
+	switch {
+	case isCustomDecoded(t):
+		// custom decoded types are handled as a single field
+	case t.Kind() == reflect.Struct:
+		if visiting[t] {
+			return fields
+		}
+
+		visiting[t] = true
+		defer delete(visiting, t)
+		for _, f := range structFields(t, o) {
+			var fv reflect.Value
+			if v.IsValid() {
+				fv = v.FieldByIndex(f.field.Index)
+			}
+
+			fk := append(key[:len(key):len(key)], f.name)
+			fields = collectFields(fields, fk, f.field.Type, fv, f.field.Tag, o, visiting)
+		}
+
+		return fields
+	case t.Kind() == reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			fk := append(key[:len(key):len(key)], mapKeyPlaceholder)
+			return collectFields(fields, fk, t.Elem(), reflect.Value{}, tag, o, visiting)
+		}
+	}
+
+	if len(key) == 0 {
+		return fields
+	}
+
+	def, ok := tag.Lookup("default")
+	if !ok {
+		def = defaultString(v)
+	}
+
+	return append(fields, Field{
+		Key:         key,
+		Type:        typeName(t),
+		Default:     def,
+		Description: tag.Get("help"),
+	})
+}
+
+// Fields returns the keys accepted by the target, with the type, the default taken from the default struct
+// tag or the current value of the field, and the description taken from the help struct tag. The keys of maps
+// are represented as <key>. To show the defaults instead of the input, it needs to be called before the
+// config is applied to the target.
+func Fields(target interface{}, o ...Option) []Field {
+	v := reflect.ValueOf(target)
+	if !v.IsValid() {
+		return nil
+	}
+
+	return collectFields(nil, nil, v.Type(), v, "", makeOptions(o), make(map[reflect.Type]bool))
+}
+
+// FlagName returns the command line flag form of the field key.
+func (f Field) FlagName(mode FlagsMode) string {
+	dash := "--"
+	if mode&SingleDash != 0 {
+		dash = "-"
+	}
+
+	return dash + strings.Join(f.Key, ".")
+}
+
+// EnvName returns the environment variable form of the field key.
+func (f Field) EnvName(prefix string) string {
+	var symbols []string
+	if prefix != "" {
+		symbols = append(symbols, strcase.ToScreamingSnake(prefix))
+	}
+
+	for _, symbol := range f.Key {
+		if symbol == mapKeyPlaceholder {
+			symbols = append(symbols, strings.ToUpper(symbol))
+			continue
+		}
+
+		symbols = append(symbols, strcase.ToScreamingSnake(symbol))
+	}
+
+	return strings.Join(symbols, "_")
+}
+
+// Help returns the usage text of the target, listing every accepted key in both flag and environment
+// variable form.
+func Help(target interface{}, envPrefix string, mode FlagsMode, o ...Option) string {
+	var b bytes.Buffer
+	b.WriteString("Options:\n")
+	for _, f := range Fields(target, o...) {
+		fmt.Fprintf(&b, "\n  %s %s\n", f.FlagName(mode), f.Type)
+		if f.Description != "" {
+			fmt.Fprintf(&b, "    \t%s\n", f.Description)
+		}
+
+		if f.Default != "" {
+			fmt.Fprintf(&b, "    \tdefault: %s\n", f.Default)
+		}
+
+		fmt.Fprintf(&b, "    \tenv: %s\n", f.EnvName(envPrefix))
+	}
+
+	help := "-h, --help"
+	if mode&SingleDash != 0 {
+		help = "-h, -help"
+	} else if mode&BanShort != 0 {
+		help = "--help"
+	}
+
+	fmt.Fprintf(&b, "\n  %s\n    \tshow help\n", help)
+	return b.String()
+}
+
+// HelpRequested tells whether the -h or --help flag was found in the flag sources used for the target.
+func HelpRequested(target interface{}, s Source, o ...Option) bool {
+	t := reflect.TypeOf(target)
+	if hs, ok := s.(helpSource); ok && hs.helpRequested(t, makeOptions(o)) {
+		return true
+	}
+
+	if cs, ok := s.(compositeSource); ok {
+		for _, si := range cs.children() {
+			if HelpRequested(target, si, o...) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
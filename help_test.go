@@ -0,0 +1,128 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHelp(t *testing.T) {
+	type options struct {
+		Source struct {
+			Kubernetes struct {
+				Enabled bool   `help:"enable the kubernetes source"`
+				ApiURL  string `help:"address of the kubernetes API"`
+			}
+		}
+
+		Port    int
+		Labels  map[string]string
+		Plugins []string
+		Next    *options
+		hidden  int
+	}
+
+	t.Run("fields", func(t *testing.T) {
+		o := options{Port: 8080}
+		f := Fields(&o)
+		if len(f) != 5 {
+			t.Fatal("failed to collect the fields", f)
+		}
+
+		enabled := f[0]
+		if strings.Join(enabled.Key, ".") != "source.kubernetes.enabled" ||
+			enabled.Type != "bool" ||
+			enabled.Default != "" ||
+			enabled.Description != "enable the kubernetes source" {
+			t.Error("failed to describe field", enabled)
+		}
+
+		if enabled.FlagName(FlagsDefault) != "--source.kubernetes.enabled" ||
+			enabled.FlagName(SingleDash) != "-source.kubernetes.enabled" {
+			t.Error("failed to return the flag name", enabled.FlagName(FlagsDefault))
+		}
+
+		if enabled.EnvName("myapp") != "MYAPP_SOURCE_KUBERNETES_ENABLED" {
+			t.Error("failed to return the env name", enabled.EnvName("myapp"))
+		}
+
+		if f[1].EnvName("") != "SOURCE_KUBERNETES_API_URL" {
+			t.Error("failed to return the env name", f[1].EnvName(""))
+		}
+
+		if f[2].Default != "8080" || f[2].Type != "int" {
+			t.Error("failed to describe field with default", f[2])
+		}
+
+		if strings.Join(f[3].Key, ".") != "labels.<key>" || f[3].EnvName("") != "LABELS_<KEY>" {
+			t.Error("failed to describe map field", f[3])
+		}
+
+		if f[4].Type != "[]string" {
+			t.Error("failed to describe list field", f[4])
+		}
+	})
+
+	t.Run("help text", func(t *testing.T) {
+		o := options{Port: 8080}
+		h := Help(&o, "myapp", FlagsDefault)
+		for _, expected := range []string{
+			"--source.kubernetes.enabled bool",
+			"enable the kubernetes source",
+			"env: MYAPP_SOURCE_KUBERNETES_ENABLED",
+			"--port int",
+			"default: 8080",
+			"-h, --help",
+		} {
+			if !strings.Contains(h, expected) {
+				t.Error("failed to generate help", expected)
+				t.Log(h)
+			}
+		}
+	})
+
+	t.Run("help requested", func(t *testing.T) {
+		for _, test := range []struct {
+			title    string
+			args     []string
+			expected bool
+		}{{
+			title: "no help",
+			args:  []string{"--port", "8080"},
+		}, {
+			title:    "long",
+			args:     []string{"--port", "8080", "--help"},
+			expected: true,
+		}, {
+			title:    "short",
+			args:     []string{"-h", "foo"},
+			expected: true,
+		}, {
+			title: "after double dash",
+			args:  []string{"--", "--help"},
+		}} {
+			t.Run(test.title, func(t *testing.T) {
+				var o options
+				s := Merge(jsonString(`{"port": 80}`), Flags(test.args, FlagsDefault))
+				if err := Apply(&o, s); err != nil {
+					t.Fatal(err)
+				}
+
+				if HelpRequested(&o, s) != test.expected {
+					t.Error("failed to detect help request")
+				}
+			})
+		}
+	})
+
+	t.Run("help field", func(t *testing.T) {
+		var o struct{ Help string }
+		s := Flags([]string{"--help", "foo"}, FlagsDefault)
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if HelpRequested(&o, s) || o.Help != "foo" {
+			t.Error("failed to apply help field", o.Help)
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Validator can be implemented by the target types to validate themselves after they were populated, e.g.
+// to check rules involving multiple fields. It is called after the fields were validated.
+type Validator interface {
+	Validate() error
+}
+
+// Defaulter can be implemented by the target types to set the defaults that can't be expressed by the
+// default struct tags. It is called after the target was populated, before validation.
+type Defaulter interface {
+	SetDefaults()
+}
+
+var (
+	validatorType = reflect.TypeOf((*Validator)(nil)).Elem()
+	defaulterType = reflect.TypeOf((*Defaulter)(nil)).Elem()
+)
+
+// wraps the errors returned by the Validate methods, so that they match both ErrValidationFailed and the
+// original error
+type validationError struct {
+	err error
+}
+
+func (e validationError) Error() string {
+	return ErrValidationFailed.Error() + ": " + e.err.Error()
+}
+
+func (e validationError) Unwrap() error        { return e.err }
+func (e validationError) Is(target error) bool { return target == ErrValidationFailed }
+
+// the methods with pointer receivers are called only on addressable values
+func hookOf(v reflect.Value, t reflect.Type) (interface{}, bool) {
+	if v.CanAddr() && implements(v.Type(), t) {
+		return v.Addr().Interface(), true
+	}
+
+	if v.Type().Implements(t) {
+		return v.Interface(), true
+	}
+
+	return nil, false
+}
+
+func callValidate(v reflect.Value, path []string) error {
+	h, ok := hookOf(v, validatorType)
+	if !ok {
+		return nil
+	}
+
+	if err := h.(Validator).Validate(); err != nil {
+		return &Error{Key: path, Err: validationError{err: err}}
+	}
+
+	return nil
+}
+
+// calls SetDefaults on the values found in the target, at any depth, the containing values first
+func setDefaults(v reflect.Value, o options) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+
+		v = v.Elem()
+	}
+
+	if h, ok := hookOf(v, defaulterType); ok {
+		h.(Defaulter).SetDefaults()
+	}
+
+	t := v.Type()
+	if t == timeType || isCustomDecoded(t) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for _, f := range structFields(t, o) {
+			setDefaults(v.FieldByIndex(f.field.Index), o)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			setDefaults(v.Index(i), o)
+		}
+	case reflect.Map:
+		// the map values are not addressable, so they are copied and set back
+		mkeys := v.MapKeys()
+		sort.Slice(mkeys, func(i, j int) bool { return mkeys[i].String() < mkeys[j].String() })
+		for _, key := range mkeys {
+			mv := reflect.New(t.Elem()).Elem()
+			mv.Set(v.MapIndex(key))
+			setDefaults(mv, o)
+			v.SetMapIndex(key, mv)
+		}
+	}
+}
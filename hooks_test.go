@@ -0,0 +1,112 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var errIncompleteTLS = errors.New("tls-cert and tls-key must be set together")
+
+type testTLS struct {
+	TLSCert, TLSKey string
+}
+
+type testListener struct {
+	Address string
+	TLS     testTLS
+}
+
+type testTimeouts struct {
+	Read, Write int
+}
+
+func (t testTLS) Validate() error {
+	if (t.TLSCert == "") != (t.TLSKey == "") {
+		return errIncompleteTLS
+	}
+
+	return nil
+}
+
+func (l *testListener) SetDefaults() {
+	if l.Address == "" {
+		l.Address = ":8080"
+	}
+}
+
+func (t *testTimeouts) SetDefaults() {
+	if t.Write == 0 {
+		t.Write = t.Read
+	}
+}
+
+func (t *testTimeouts) Validate() error {
+	if t.Read < 0 {
+		return errors.New("negative timeout")
+	}
+
+	return nil
+}
+
+func TestHooks(t *testing.T) {
+	t.Run("set defaults", func(t *testing.T) {
+		var o struct {
+			Listeners []testListener
+			Timeouts  map[string]testTimeouts
+		}
+
+		s := jsonString(`{
+			"listeners": [{"address": ":9090"}, {}],
+			"timeouts": {"backend": {"read": 3}}
+		}`)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Listeners) != 2 || o.Listeners[0].Address != ":9090" || o.Listeners[1].Address != ":8080" {
+			t.Error("failed to set defaults", o.Listeners)
+		}
+
+		if o.Timeouts["backend"].Write != 3 {
+			t.Error("failed to set defaults of map values", o.Timeouts)
+		}
+	})
+
+	t.Run("valid", func(t *testing.T) {
+		var o struct{ Listener testListener }
+		s := jsonString(`{"listener": {"tls": {"tls-cert": "cert.pem", "tls-key": "key.pem"}}}`)
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var o struct{ Listener testListener }
+		s := jsonString(`{"listener": {"tls": {"tls-cert": "cert.pem"}}}`)
+		err := Apply(&o, s)
+		if !errors.Is(err, ErrValidationFailed) || !errors.Is(err, errIncompleteTLS) {
+			t.Fatal("failed to fail with the right error", err)
+		}
+
+		if !strings.HasPrefix(failingRule(err), "listener.tls: ") {
+			t.Error("failed to annotate the error with the key path", err)
+		}
+	})
+
+	t.Run("pointer receiver in map", func(t *testing.T) {
+		var o struct{ Timeouts map[string]testTimeouts }
+		err := Apply(&o, jsonString(`{"timeouts": {"backend": {"read": -1}}}`))
+		if !errors.Is(err, ErrValidationFailed) || !strings.HasPrefix(failingRule(err), "timeouts.backend: ") {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("root", func(t *testing.T) {
+		o := testTLS{TLSKey: "key.pem"}
+		if err := Apply(&o, Env("myapp", []string{})); !errors.Is(err, errIncompleteTLS) {
+			t.Error("failed to validate the root", err)
+		}
+	})
+}
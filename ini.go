@@ -21,9 +21,7 @@ type iniNode struct {
 
 type iniSource struct {
 	input io.Reader
-	done bool
-	result Node
-	err error
+	cache readCache
 }
 
 var errValuesAndFields = errors.New("values for a key with child keys not accepted")
@@ -33,10 +31,26 @@ func (n iniNode) Len() int               { return len(n.ini.Values) }
 func (n iniNode) Field(key string) Node  { return iniNode{ini: n.ini.Fields[key]} }
 
 func (n iniNode) Item(i int) Node {
-	return iniNode{
-		ini: &ini.Node{Values: n.ini.Values[i : i+1]},
-		typ: Primitive,
+	item := &ini.Node{Values: n.ini.Values[i : i+1]}
+	if i < len(n.ini.Positions) {
+		item.Positions = n.ini.Positions[i : i+1]
 	}
+
+	return iniNode{ini: item, typ: Primitive}
+}
+
+// the position of the first value, when known
+func (n iniNode) origin() origin {
+	if n.ini == nil || len(n.ini.Positions) == 0 {
+		return origin{}
+	}
+
+	p := n.ini.Positions[0]
+	return origin{line: p.Line, column: p.Column}
+}
+
+func (n iniNode) isUnset() bool {
+	return n.ini != nil && n.ini.Unset && len(n.ini.Values) == 0 && len(n.ini.Fields) == 0
 }
 
 func (n iniNode) Type() NodeType {
@@ -44,6 +58,10 @@ func (n iniNode) Type() NodeType {
 		return n.typ
 	}
 
+	if n.isUnset() {
+		return Nil
+	}
+
 	return any
 }
 
@@ -56,20 +74,24 @@ func (n iniNode) Keys() []string {
 	return keys
 }
 
-func (s *iniSource) Read() (Node, error) {
-	if s.done {
-		return s.result, s.err
-	}
-
-	s.done = true
+func (s *iniSource) read() (Node, error) {
 	n, err := ini.Read(s.input)
 	if err != nil {
-		s.err = err
 		return nil, err
 	}
 
-	s.result = iniNode{ini: n}
-	return s.result, nil
+	return iniNode{ini: n}, nil
+}
+
+func (s *iniSource) Read() (Node, error) {
+	return s.cache.read(s.read)
+}
+
+// resets the cache only when the input can be read again
+func (s *iniSource) reset() {
+	if rewind(s.input) {
+		s.cache.reset()
+	}
 }
 
 func INI(r io.Reader) Source { return &iniSource{input: r} }
@@ -6,9 +6,22 @@ import (
 	"github.com/aryszka/config/ini/syntax"
 )
 
+// Position is the line and the column of a value in the input, both starting from 1.
+type Position struct {
+	Line, Column int
+}
+
 type Node struct {
 	Values []string
+
+	// Positions contains the positions of the values, when known.
+	Positions []Position
+
 	Fields map[string]*Node
+
+	// Unset is true when the values and the fields of the node were cleared with the !unset marker, e.g.
+	// foo = !unset.
+	Unset bool
 }
 
 func Read(r io.Reader) (*Node, error) {
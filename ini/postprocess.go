@@ -2,25 +2,59 @@ package ini
 
 import (
 	"errors"
+	"sort"
 
 	"github.com/aryszka/config/ini/syntax"
 )
 
+// an unquoted value clearing the values and the fields of the key defined before it
+const unsetMarker = "!unset"
+
 var errUnexpectedParserResult = errors.New("unexpected parser result")
 
-func processQuote(parent *Node, n *syntax.Node) error {
+// the offsets of the line starts, calculated once per input
+type lineStarts []int
+
+func findLineStarts(n *syntax.Node) lineStarts {
+	l := lineStarts{0}
+	for i, r := range n.Tokens() {
+		if r == '\n' {
+			l = append(l, i+1)
+		}
+	}
+
+	return l
+}
+
+func (l lineStarts) position(n *syntax.Node) Position {
+	line := sort.Search(len(l), func(i int) bool { return l[i] > n.From })
+	return Position{Line: line, Column: n.From - l[line-1] + 1}
+}
+
+func addValue(l lineStarts, parent *Node, n *syntax.Node, text string) {
+	parent.Values = append(parent.Values, text)
+	parent.Positions = append(parent.Positions, l.position(n))
+}
+
+func processQuote(l lineStarts, parent *Node, n *syntax.Node) error {
 	text, err := unquote(n.Text())
 	if err != nil {
 		return err
 	}
 
-	parent.Values = append(parent.Values, text)
+	addValue(l, parent, n, text)
 	return nil
 }
 
-func processValue(parent *Node, n *syntax.Node) error {
+func processValue(l lineStarts, parent *Node, n *syntax.Node) error {
 	if len(n.Nodes) > 0 {
-		return processNode(parent, n.Nodes[0])
+		return processNode(l, parent, n.Nodes[0])
+	}
+
+	if n.Text() == unsetMarker {
+		parent.Values, parent.Positions, parent.Fields = nil, nil, nil
+		parent.Unset = true
+		return nil
 	}
 
 	text, err := unescapeNonQuote(n.Text())
@@ -28,7 +62,7 @@ func processValue(parent *Node, n *syntax.Node) error {
 		return err
 	}
 
-	parent.Values = append(parent.Values, text)
+	addValue(l, parent, n, text)
 	return nil
 }
 
@@ -59,7 +93,7 @@ func getOrCreateChild(n *Node, key []string) *Node {
 	return getOrCreateChild(child, key[1:])
 }
 
-func processKeyedValue(parent *Node, n *syntax.Node) error {
+func processKeyedValue(l lineStarts, parent *Node, n *syntax.Node) error {
 	if len(n.Nodes) < 2 {
 		// TODO: error info
 		return errUnexpectedParserResult
@@ -67,12 +101,12 @@ func processKeyedValue(parent *Node, n *syntax.Node) error {
 
 	key := getKey(n.Nodes[0])
 	child := getOrCreateChild(parent, key)
-	return processNode(child, n.Nodes[1])
+	return processNode(l, child, n.Nodes[1])
 }
 
-func processNodes(parent *Node, n []*syntax.Node) error {
+func processNodes(l lineStarts, parent *Node, n []*syntax.Node) error {
 	for i := range n {
-		if err := processNode(parent, n[i]); err != nil {
+		if err := processNode(l, parent, n[i]); err != nil {
 			return err
 		}
 	}
@@ -80,7 +114,7 @@ func processNodes(parent *Node, n []*syntax.Node) error {
 	return nil
 }
 
-func processGroup(parent *Node, n *syntax.Node) error {
+func processGroup(l lineStarts, parent *Node, n *syntax.Node) error {
 	if len(n.Nodes) == 0 || len(n.Nodes[0].Nodes) == 0 {
 		// TODO: error info
 		return errUnexpectedParserResult
@@ -88,25 +122,25 @@ func processGroup(parent *Node, n *syntax.Node) error {
 
 	key := getKey(n.Nodes[0].Nodes[0])
 	child := getOrCreateChild(parent, key)
-	return processNodes(child, n.Nodes[1:])
+	return processNodes(l, child, n.Nodes[1:])
 }
 
-func processConfig(parent *Node, n *syntax.Node) error {
-	return processNodes(parent, n.Nodes)
+func processConfig(l lineStarts, parent *Node, n *syntax.Node) error {
+	return processNodes(l, parent, n.Nodes)
 }
 
-func processNode(parent *Node, n *syntax.Node) error {
+func processNode(l lineStarts, parent *Node, n *syntax.Node) error {
 	switch n.Name {
 	case "quote":
-		return processQuote(parent, n)
+		return processQuote(l, parent, n)
 	case "value":
-		return processValue(parent, n)
+		return processValue(l, parent, n)
 	case "keyed-value":
-		return processKeyedValue(parent, n)
+		return processKeyedValue(l, parent, n)
 	case "group":
-		return processGroup(parent, n)
+		return processGroup(l, parent, n)
 	case "config":
-		return processConfig(parent, n)
+		return processConfig(l, parent, n)
 	default:
 		// TODO: error info
 		return errUnexpectedParserResult
@@ -115,6 +149,6 @@ func processNode(parent *Node, n *syntax.Node) error {
 
 func postprocess(n *syntax.Node) (*Node, error) {
 	root := &Node{}
-	err := processNode(root, n)
+	err := processNode(findLineStarts(n), root, n)
 	return root, err
 }
@@ -31,6 +31,8 @@ func (l *jsonReader) Read() (interface{}, error) {
 	return o, err
 }
 
+func (l *jsonReader) rewind() bool { return rewind(l.input) }
+
 func (l jsonReader) TypeMapping() map[NodeType]NodeType {
 	return l.typeMapping
 }
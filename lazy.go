@@ -0,0 +1,165 @@
+package config
+
+import (
+	"strconv"
+	"sync"
+)
+
+// LazyReader can be implemented by the backends that fetch the config on demand, e.g. databases or remote
+// key-value stores. The values are addressed by their path from the root, where the list items are
+// represented by their index. When applying, only those parts of the config are fetched that the target has
+// fields for, unless the source is strict.
+type LazyReader interface {
+
+	// Type returns the type of the value at the path. For the empty root, it should return ErrNoConfig.
+	Type(path []string) (NodeType, error)
+
+	// Primitive returns the primitive value at the path, of the types supported by Node.
+	Primitive(path []string) (interface{}, error)
+
+	// Len returns the number of the list items at the path.
+	Len(path []string) (int, error)
+
+	// Keys returns the keys of the structure at the path.
+	Keys(path []string) ([]string, error)
+}
+
+type lazySource struct {
+	reader LazyReader
+}
+
+// the values are fetched once per node, and the failures are stored, so that they can be reported by apply
+type lazyNode struct {
+	reader LazyReader
+	path   []string
+
+	mx        sync.Mutex
+	fetched   map[string]bool
+	typ       NodeType
+	primitive interface{}
+	length    int
+	keys      []string
+	items     map[int]*lazyNode
+	fields    map[string]*lazyNode
+	err       error
+}
+
+// implemented by the nodes that can fail to provide their values, e.g. the lazy nodes
+type failingNode interface {
+	failure() error
+}
+
+func newLazyNode(r LazyReader, path []string) *lazyNode {
+	return &lazyNode{
+		reader:  r,
+		path:    path,
+		fetched: make(map[string]bool),
+		items:   make(map[int]*lazyNode),
+		fields:  make(map[string]*lazyNode),
+	}
+}
+
+func (n *lazyNode) fetch(what string, f func() error) {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	if n.fetched[what] {
+		return
+	}
+
+	n.fetched[what] = true
+	if err := f(); err != nil && n.err == nil {
+		n.err = err
+	}
+}
+
+func (n *lazyNode) failure() error {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	return n.err
+}
+
+func (n *lazyNode) Type() NodeType {
+	n.fetch("type", func() (err error) {
+		n.typ, err = n.reader.Type(n.path)
+		return
+	})
+
+	return n.typ
+}
+
+func (n *lazyNode) Primitive() interface{} {
+	n.fetch("primitive", func() (err error) {
+		n.primitive, err = n.reader.Primitive(n.path)
+		return
+	})
+
+	return n.primitive
+}
+
+func (n *lazyNode) Len() int {
+	n.fetch("len", func() (err error) {
+		n.length, err = n.reader.Len(n.path)
+		return
+	})
+
+	return n.length
+}
+
+func (n *lazyNode) Keys() []string {
+	n.fetch("keys", func() (err error) {
+		n.keys, err = n.reader.Keys(n.path)
+		return
+	})
+
+	return n.keys
+}
+
+func (n *lazyNode) child(symbol string) *lazyNode {
+	return newLazyNode(n.reader, append(n.path[:len(n.path):len(n.path)], symbol))
+}
+
+func (n *lazyNode) Item(i int) Node {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	item, ok := n.items[i]
+	if !ok {
+		item = n.child(strconv.Itoa(i))
+		n.items[i] = item
+	}
+
+	return item
+}
+
+func (n *lazyNode) Field(key string) Node {
+	n.mx.Lock()
+	defer n.mx.Unlock()
+	field, ok := n.fields[key]
+	if !ok {
+		field = n.child(key)
+		n.fields[key] = field
+	}
+
+	return field
+}
+
+func failureOf(n Node) error {
+	if fn, ok := n.(failingNode); ok {
+		return fn.failure()
+	}
+
+	return nil
+}
+
+func (s lazySource) Read() (Node, error) {
+	n := newLazyNode(s.reader, nil)
+	if n.Type(); n.failure() != nil {
+		return nil, n.failure()
+	}
+
+	return n, nil
+}
+
+// WithLazyReader creates a source that fetches the config from the reader on demand.
+func WithLazyReader(r LazyReader) Source {
+	return lazySource{reader: r}
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+type testBackend struct {
+	data    interface{}
+	fail    map[string]bool
+	fetched map[string]int
+}
+
+var errTestBackend = errors.New("test backend")
+
+func (b *testBackend) lookup(path []string) (interface{}, error) {
+	key := strings.Join(path, ".")
+	if b.fetched == nil {
+		b.fetched = make(map[string]int)
+	}
+
+	b.fetched[key]++
+	if b.fail[key] {
+		return nil, errTestBackend
+	}
+
+	v := b.data
+	for _, p := range path {
+		switch vt := v.(type) {
+		case map[string]interface{}:
+			v = vt[p]
+		case []interface{}:
+			i, err := strconv.Atoi(p)
+			if err != nil || i >= len(vt) {
+				return nil, errTestBackend
+			}
+
+			v = vt[i]
+		default:
+			return nil, errTestBackend
+		}
+	}
+
+	return v, nil
+}
+
+func (b *testBackend) Type(path []string) (NodeType, error) {
+	v, err := b.lookup(path)
+	if err != nil {
+		return undefined, err
+	}
+
+	switch v.(type) {
+	case nil:
+		if len(path) == 0 {
+			return undefined, ErrNoConfig
+		}
+
+		return Nil, nil
+	case bool:
+		return Bool, nil
+	case int:
+		return Int, nil
+	case string:
+		return String, nil
+	case []interface{}:
+		return List, nil
+	default:
+		return Structure, nil
+	}
+}
+
+func (b *testBackend) Primitive(path []string) (interface{}, error) {
+	return b.lookup(path)
+}
+
+func (b *testBackend) Len(path []string) (int, error) {
+	v, err := b.lookup(path)
+	if err != nil {
+		return 0, err
+	}
+
+	l, _ := v.([]interface{})
+	return len(l), nil
+}
+
+func (b *testBackend) Keys(path []string) ([]string, error) {
+	v, err := b.lookup(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	m, _ := v.(map[string]interface{})
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+func TestLazyReader(t *testing.T) {
+	type server struct {
+		Port  int
+		Hosts []string
+	}
+
+	type target struct {
+		Server server
+		Debug  bool
+	}
+
+	data := func() map[string]interface{} {
+		return map[string]interface{}{
+			"server": map[string]interface{}{
+				"port":  8080,
+				"hosts": []interface{}{"foo", "bar"},
+			},
+			"debug": true,
+			"other": map[string]interface{}{
+				"huge": map[string]interface{}{"foo": "bar"},
+			},
+		}
+	}
+
+	t.Run("apply", func(t *testing.T) {
+		b := &testBackend{data: data()}
+		var o target
+		if err := Apply(&o, WithLazyReader(b)); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Server.Port != 8080 || len(o.Server.Hosts) != 2 || o.Server.Hosts[1] != "bar" || !o.Debug {
+			t.Error("failed to apply the lazy source", o)
+		}
+
+		for key := range b.fetched {
+			if strings.HasPrefix(key, "other") {
+				t.Error("fetched an unused subtree", key)
+			}
+		}
+	})
+
+	t.Run("fetched once", func(t *testing.T) {
+		b := &testBackend{data: data()}
+		n, err := WithLazyReader(b).Read()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		n.Field("server").Field("port").Type()
+		n.Field("server").Field("port").Type()
+		if b.fetched["server.port"] != 1 {
+			t.Error("failed to memoize the fetched values", b.fetched)
+		}
+	})
+
+	t.Run("merged with eager source", func(t *testing.T) {
+		b := &testBackend{data: data()}
+		var o target
+		if err := Apply(&o, Merge(WithLazyReader(b), jsonString(`{"server": {"port": 9090}}`))); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Server.Port != 9090 || len(o.Server.Hosts) != 2 || !o.Debug {
+			t.Error("failed to merge the lazy source", o)
+		}
+	})
+
+	t.Run("merged fetches only the applied keys", func(t *testing.T) {
+		b := &testBackend{data: data()}
+		var o struct{ Debug bool }
+		if err := Apply(&o, Merge(jsonString(`{"server": {"port": 9090}}`), WithLazyReader(b))); err != nil {
+			t.Fatal(err)
+		}
+
+		if !o.Debug {
+			t.Error("failed to apply the lazy source", o)
+		}
+
+		for key := range b.fetched {
+			if key != "" && key != "debug" {
+				t.Error("fetched an unused key", key)
+			}
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		o := target{Debug: true}
+		if err := Apply(&o, WithLazyReader(&testBackend{})); err != nil || !o.Debug {
+			t.Error("failed to handle the empty backend", o, err)
+		}
+	})
+
+	t.Run("root fails", func(t *testing.T) {
+		b := &testBackend{data: data(), fail: map[string]bool{"": true}}
+		if err := Apply(&target{}, WithLazyReader(b)); !errors.Is(err, errTestBackend) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("fetch fails", func(t *testing.T) {
+		b := &testBackend{data: data(), fail: map[string]bool{"server.hosts": true}}
+		err := Apply(&target{}, WithLazyReader(b))
+		if !errors.Is(err, errTestBackend) {
+			t.Fatal("failed to fail with the right error", err)
+		}
+
+		var e *Error
+		if !errors.As(err, &e) || strings.Join(e.Key, ".") != "server.hosts" {
+			t.Error("failed to report the key path", err)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Reloader holds the last successfully applied config, and reloads it when a signal is received.
+type Reloader struct {
+	target      reflect.Value
+	source      Source
+	options     []Option
+	current     atomic.Value
+	reloadMx    sync.Mutex
+	subscribers subscribers
+	signals     chan os.Signal
+	stopOnce    sync.Once
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func (r *Reloader) run() {
+	defer close(r.done)
+	for {
+		select {
+		case <-r.signals:
+			r.Reload()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// ReloadOnSignal applies the source to a copy of the target, and applies it again to a new copy every time
+// one of the signals is received. When no signals are set, it reloads on SIGHUP. The sources are read again
+// on every reload, including the files and the readers whose input is seekable. When the initial apply
+// fails, it returns the error. When a reload fails, the last successfully applied config is kept. The target
+// itself is not changed, it serves as the initial value of the copies, e.g. holding the defaults, and it
+// should not be changed by the caller while reloading either. The reloader needs to be stopped with Stop.
+func ReloadOnSignal(target interface{}, s Source, signals []os.Signal, o ...Option) (*Reloader, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, invalidTarget(reflect.TypeOf(target))
+	}
+
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	r := &Reloader{
+		target:  v,
+		source:  s,
+		options: o,
+		signals: make(chan os.Signal, 1),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	initial, err := reload(v, s, o)
+	if err != nil {
+		return nil, err
+	}
+
+	r.current.Store(initial)
+	signal.Notify(r.signals, signals...)
+	go r.run()
+	return r, nil
+}
+
+// Value returns the last successfully applied config, as a pointer of the same type as the target passed
+// to ReloadOnSignal. It is safe to call from multiple goroutines, but the returned value must not be
+// changed.
+func (r *Reloader) Value() interface{} {
+	return r.current.Load()
+}
+
+// Reload reads the sources again and applies them to a new copy of the target. When applying, including the
+// validation, succeeds, the new config replaces the current one. Otherwise the current one is kept, and the
+// error is returned. The subscribers are notified in both cases.
+func (r *Reloader) Reload() error {
+	r.reloadMx.Lock()
+	defer r.reloadMx.Unlock()
+	v, err := reload(r.target, r.source, r.options)
+	if err == nil {
+		r.current.Store(v)
+	}
+
+	r.subscribers.notify(Update{Value: v, Err: err})
+	return err
+}
+
+// Subscribe registers a function to be called after every reload.
+func (r *Reloader) Subscribe(f func(Update)) { r.subscribers.add(f) }
+
+// Stop stops listening to the signals.
+func (r *Reloader) Stop() {
+	r.stopOnce.Do(func() {
+		signal.Stop(r.signals)
+		close(r.stop)
+	})
+
+	<-r.done
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestReload(t *testing.T) {
+	type target struct {
+		Foo  int `max:"100"`
+		Bar  string
+		Path string
+	}
+
+	t.Run("initial failure", func(t *testing.T) {
+		if _, err := ReloadOnSignal(&target{}, jsonString(`{"foo": 101}`), nil); !errors.Is(err, ErrValidationFailed) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("keep last known good", func(t *testing.T) {
+		withTestFiles(t, map[string]string{"config.ini": "foo = 21"}, func(dir string) {
+			name := filepath.Join(dir, "config.ini")
+			json := strings.NewReader(`{"bar": "baz"}`)
+			r, err := ReloadOnSignal(&target{Path: "/tmp"}, Merge(File(name), JSON(json)), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer r.Stop()
+			var updates []Update
+			r.Subscribe(func(u Update) { updates = append(updates, u) })
+
+			if err := ioutil.WriteFile(name, []byte("foo = 101"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := r.Reload(); !errors.Is(err, ErrValidationFailed) {
+				t.Fatal("failed to fail with the right error", err)
+			}
+
+			if o := r.Value().(*target); o.Foo != 21 || o.Bar != "baz" || o.Path != "/tmp" {
+				t.Error("failed to keep the last known good config", o)
+			}
+
+			if err := ioutil.WriteFile(name, []byte("foo = 42"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := r.Reload(); err != nil {
+				t.Fatal(err)
+			}
+
+			if o := r.Value().(*target); o.Foo != 42 || o.Bar != "baz" || o.Path != "/tmp" {
+				t.Error("failed to reload", o)
+			}
+
+			if len(updates) != 2 || updates[0].Err == nil || updates[1].Value != r.Value() {
+				t.Error("failed to notify the subscribers", updates)
+			}
+		})
+	})
+
+	t.Run("signal", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("signals not supported")
+		}
+
+		withTestFiles(t, map[string]string{"config.json": `{"foo": 21}`}, func(dir string) {
+			name := filepath.Join(dir, "config.json")
+			r, err := ReloadOnSignal(&target{}, File(name), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer r.Stop()
+			u := make(chan Update, 1)
+			r.Subscribe(func(ui Update) { u <- ui })
+
+			if err := ioutil.WriteFile(name, []byte(`{"foo": 42}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			p, err := os.FindProcess(os.Getpid())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if err := p.Signal(syscall.SIGHUP); err != nil {
+				t.Fatal(err)
+			}
+
+			select {
+			case ui := <-u:
+				if ui.Err != nil || r.Value().(*target).Foo != 42 {
+					t.Error("failed to reload on signal", ui.Err, r.Value())
+				}
+			case <-time.After(3 * time.Second):
+				t.Fatal("timeout")
+			}
+		})
+	})
+}
@@ -3,12 +3,11 @@ package config
 import (
 	"errors"
 	"fmt"
+	"io"
+	"reflect"
+	"sync"
 )
 
-// TODO: gradual reader may be required because when reading from a database, we may not want to read everything
-// if it's not required. An additional reader node type may be the right solution. This will be more interesting
-// when it will be possible to update the config on-the-fly.
-
 type Reader interface {
 	Read() (interface{}, error)
 	TypeMapping() map[NodeType]NodeType
@@ -45,14 +44,39 @@ type Source interface {
 	Read() (Node, error)
 }
 
-// TODO: split source and node
+// implemented by sources whose parsing depends on the type of the target, e.g. the flags
+type typedSource interface {
+	readTyped(reflect.Type, options) (Node, error)
+}
+
+// implemented by the sources combining other sources, e.g. Merge and Override
+type compositeSource interface {
+	children() []Source
+}
+
+// implemented by sources that can have positional arguments, e.g. the flags
+type positionalSource interface {
+	positional(reflect.Type, options) ([]string, error)
+}
+
 type source struct {
-	reader      Reader
-	typeMapping map[NodeType]NodeType
+	reader Reader
+	name   string
+	cache  readCache
+}
+
+type readerNode struct {
 	node        interface{}
+	typeMapping map[NodeType]NodeType
 	name        string
-	hasRead bool
-	err error
+}
+
+// caches the result of the first read until reset, safe to use from multiple goroutines
+type readCache struct {
+	mx     sync.Mutex
+	done   bool
+	result Node
+	err    error
 }
 
 var (
@@ -69,10 +93,10 @@ func WithReader(l Reader) Source {
 	return &source{reader: l}
 }
 
-func (s source) sourceErrorf(format string, args ...interface{}) error {
-	if s.name != "" {
+func namedSourceErrorf(name, format string, args ...interface{}) error {
+	if name != "" {
 		format = "source=%s; " + format
-		args = append([]interface{}{s.name}, args...)
+		args = append([]interface{}{name}, args...)
 	}
 
 	return fmt.Errorf(
@@ -81,31 +105,85 @@ func (s source) sourceErrorf(format string, args ...interface{}) error {
 	)
 }
 
-func (s source) sourceError(err error) error {
-	return s.sourceErrorf("%w", err)
+func (s *source) sourceName() string { return s.name }
+
+func (s *source) sourceError(err error) error {
+	return namedSourceErrorf(s.name, "%w", err)
 }
 
-func (s *source) Read() (Node, error) {
-	if s.hasRead && s.err != nil {
-		return nil, s.err
+func readFor(s Source, t reflect.Type, o options) (n Node, err error) {
+	if ts, ok := s.(typedSource); ok {
+		n, err = ts.readTyped(t, o)
+	} else {
+		n, err = s.Read()
 	}
 
-	if s.hasRead {
-		return source{node: s.node, typeMapping: s.reader.TypeMapping()}, nil
+	return withSourceName(n, sourceName(s)), err
+}
+
+func positionalOf(s Source, t reflect.Type, o options) ([]string, error) {
+	if ps, ok := s.(positionalSource); ok {
+		return ps.positional(t, o)
 	}
 
-	s.hasRead = true
+	return nil, nil
+}
+
+// implemented by the readers whose input can be read again, e.g. when it is a file or a bytes.Reader
+type rewindableReader interface {
+	rewind() bool
+}
+
+// rewinds the input to the start, when it is seekable
+func rewind(r io.Reader) bool {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return false
+	}
+
+	_, err := s.Seek(0, io.SeekStart)
+	return err == nil
+}
+
+func (c *readCache) read(f func() (Node, error)) (Node, error) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	if !c.done {
+		c.done = true
+		c.result, c.err = f()
+	}
+
+	return c.result, c.err
+}
+
+func (c *readCache) reset() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+	c.done = false
+	c.result, c.err = nil, nil
+}
+
+// resets the cache only when the input can be read again
+func (s *source) reset() {
+	if rr, ok := s.reader.(rewindableReader); ok && rr.rewind() {
+		s.cache.reset()
+	}
+}
+
+func (s *source) read() (Node, error) {
 	node, err := s.reader.Read()
 	if err != nil {
-		s.err = s.sourceError(err)
-		return nil, s.err
+		return nil, s.sourceError(err)
 	}
 
-	s.node = node
-	return source{node: node, typeMapping: s.reader.TypeMapping()}, nil
+	return readerNode{node: node, typeMapping: s.reader.TypeMapping(), name: s.name}, nil
 }
 
-func (s source) defaultType() NodeType {
+func (s *source) Read() (Node, error) {
+	return s.cache.read(s.read)
+}
+
+func (s readerNode) defaultType() NodeType {
 	switch s.node.(type) {
 	case nil:
 		return Nil
@@ -131,7 +209,8 @@ func (s source) defaultType() NodeType {
 	case map[string]interface{}:
 		return Structure
 	default:
-		panic(s.sourceErrorf(
+		panic(namedSourceErrorf(
+			s.name,
 			"%w; unexpected type: %v",
 			ErrSourceImplementation,
 			s.node,
@@ -139,7 +218,7 @@ func (s source) defaultType() NodeType {
 	}
 }
 
-func (s source) Type() NodeType {
+func (s readerNode) Type() NodeType {
 	dt := s.defaultType()
 	t, ok := s.typeMapping[dt]
 	if !ok {
@@ -149,19 +228,22 @@ func (s source) Type() NodeType {
 	return t
 }
 
-func (s source) Primitive() interface{} {
+// null in JSON and YAML
+func (s readerNode) isUnset() bool { return s.node == nil }
+
+func (s readerNode) Primitive() interface{} {
 	return s.node
 }
 
-func (s source) Len() int {
+func (s readerNode) Len() int {
 	return len(s.node.([]interface{}))
 }
 
-func (s source) Item(i int) Node {
-	return source{node: s.node.([]interface{})[i], typeMapping: s.typeMapping}
+func (s readerNode) Item(i int) Node {
+	return readerNode{node: s.node.([]interface{})[i], typeMapping: s.typeMapping, name: s.name}
 }
 
-func (s source) Keys() []string {
+func (s readerNode) Keys() []string {
 	var keys []string
 	for key := range s.node.(map[string]interface{}) {
 		keys = append(keys, key)
@@ -170,6 +252,6 @@ func (s source) Keys() []string {
 	return keys
 }
 
-func (s source) Field(key string) Node {
-	return source{node: s.node.(map[string]interface{})[key], typeMapping: s.typeMapping}
+func (s readerNode) Field(key string) Node {
+	return readerNode{node: s.node.(map[string]interface{})[key], typeMapping: s.typeMapping, name: s.name}
 }
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aryszka/config/keys"
+)
+
+type strictnessSource struct {
+	source Source
+	strict bool
+}
+
+// implemented by the sources that use only one of the combined sources, e.g. Override
+type selectingSource interface {
+	selected(reflect.Type, options) (Source, Node, error)
+}
+
+// implemented by sources that have a name to be used in the error messages, e.g. the files
+type namedSource interface {
+	sourceName() string
+}
+
+var ErrUnexpectedKeys = errors.New("unexpected keys")
+
+func unexpectedKeys(k []string) error {
+	return fmt.Errorf("%w: %s", ErrUnexpectedKeys, strings.Join(k, ", "))
+}
+
+func (s strictnessSource) Read() (Node, error) { return s.source.Read() }
+func (s strictnessSource) readTyped(t reflect.Type, o options) (Node, error) {
+	return readFor(s.source, t, o)
+}
+func (s strictnessSource) positional(t reflect.Type, o options) ([]string, error) {
+	return positionalOf(s.source, t, o)
+}
+func (s strictnessSource) children() []Source { return []Source{s.source} }
+
+func sourceName(s Source) string {
+	if ns, ok := s.(namedSource); ok {
+		return ns.sourceName()
+	}
+
+	return ""
+}
+
+func nodeUnexpected(t reflect.Type, o options, n Node, path, u []string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// the custom unmarshalers are responsible for their own keys, while the text unmarshalers accept only
+	// primitive values
+	if isUnmarshaler(t) || isJSONUnmarshaler(t) {
+		return u
+	}
+
+	kind := t.Kind()
+	if isTextUnmarshaler(t) {
+		kind = reflect.String
+	}
+
+	nt := n.Type()
+	if kind == reflect.Slice && nt&List != 0 {
+		for i := 0; i < n.Len(); i++ {
+			u = nodeUnexpected(t.Elem(), o, n.Item(i), append(path[:len(path):len(path)], strconv.Itoa(i)), u)
+		}
+	}
+
+	if nt&Structure == 0 {
+		return u
+	}
+
+	nkeys := n.Keys()
+	sort.Strings(nkeys)
+	switch kind {
+	case reflect.Interface:
+		return u
+	case reflect.Struct:
+		fields := fieldsByKey(t, o)
+		for _, key := range nkeys {
+			kp := append(path[:len(path):len(path)], key)
+			f, ok := fields[keys.CanonicalSymbol(key)]
+			if !ok {
+				u = append(u, strings.Join(kp, "."))
+				continue
+			}
+
+			u = nodeUnexpected(f.Type, o, n.Field(key), kp, u)
+		}
+
+		return u
+	case reflect.Map:
+		if t.Key().Kind() == reflect.String {
+			for _, key := range nkeys {
+				u = nodeUnexpected(t.Elem(), o, n.Field(key), append(path[:len(path):len(path)], key), u)
+			}
+
+			return u
+		}
+	}
+
+	for _, key := range nkeys {
+		u = append(u, strings.Join(append(path[:len(path):len(path)], key), "."))
+	}
+
+	return u
+}
+
+func collectUnexpected(s Source, t reflect.Type, o options, strict bool) ([]string, error) {
+	if ss, ok := s.(strictnessSource); ok {
+		return collectUnexpected(ss.source, t, o, ss.strict)
+	}
+
+	if ss, ok := s.(selectingSource); ok {
+		si, _, err := ss.selected(t, o)
+		if errors.Is(err, ErrNoConfig) {
+			return nil, nil
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		return collectUnexpected(si, t, o, strict)
+	}
+
+	if cs, ok := s.(compositeSource); ok {
+		var u []string
+		for _, si := range cs.children() {
+			ui, err := collectUnexpected(si, t, o, strict)
+			if err != nil {
+				return nil, err
+			}
+
+			u = append(u, ui...)
+		}
+
+		return u, nil
+	}
+
+	if !strict {
+		return nil, nil
+	}
+
+	n, err := readFor(s, t, o)
+	if n == nil && err == nil || errors.Is(err, ErrNoConfig) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	u := nodeUnexpected(t, o, n, nil, nil)
+	if name := sourceName(s); name != "" {
+		for i := range u {
+			u[i] = fmt.Sprintf("%s (source=%s)", u[i], name)
+		}
+	}
+
+	return u, nil
+}
+
+func checkUnexpected(s Source, t reflect.Type, o options) error {
+	u, err := collectUnexpected(s, t, o, false)
+	if err != nil {
+		return err
+	}
+
+	if len(u) > 0 {
+		return unexpectedKeys(u)
+	}
+
+	return nil
+}
+
+// Strict marks the source, and the sources combined in it, as strict. Applying a strict source fails with
+// ErrUnexpectedKeys when it contains keys that the target doesn't have fields for. Environment variable
+// sources are typically not suitable to be strict.
+func Strict(s Source) Source { return strictnessSource{source: s, strict: true} }
+
+// Lenient marks the source, and the sources combined in it, as not strict, even when it is combined in a
+// strict source.
+func Lenient(s Source) Source { return strictnessSource{source: s} }
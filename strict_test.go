@@ -0,0 +1,113 @@
+package config
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStrict(t *testing.T) {
+	type options struct {
+		TLSCert string
+		Routes  []struct{ Path string }
+		Labels  map[string]string
+		Any     interface{}
+	}
+
+	t.Run("lenient by default", func(t *testing.T) {
+		var o options
+		if err := Apply(&o, iniString("tls-cer = foo")); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("no unexpected keys", func(t *testing.T) {
+		var o options
+		s := Strict(jsonString(`{
+			"tlsCert": "foo",
+			"routes": [{"path": "/"}],
+			"labels": {"foo": "bar"},
+			"any": {"foo": "bar"}
+		}`))
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.TLSCert != "foo" || len(o.Routes) != 1 || o.Labels["foo"] != "bar" {
+			t.Error("failed to apply strict source", o)
+		}
+	})
+
+	t.Run("unexpected keys", func(t *testing.T) {
+		o := options{TLSCert: "bar"}
+		s := Strict(jsonString(`{
+			"tls-cer": "foo",
+			"routes": [{"path": "/", "method": "GET"}],
+			"labels": {"foo": {"bar": "baz"}}
+		}`))
+
+		err := Apply(&o, s)
+		if !errors.Is(err, ErrUnexpectedKeys) {
+			t.Fatal("failed to fail with the right error", err)
+		}
+
+		for _, key := range []string{"tls-cer", "routes.0.method", "labels.foo.bar"} {
+			if !strings.Contains(err.Error(), key) {
+				t.Error("failed to report unexpected key", key, err)
+			}
+		}
+
+		if o.TLSCert != "bar" {
+			t.Error("failed to leave the target unchanged")
+		}
+	})
+
+	t.Run("per source", func(t *testing.T) {
+		withTestFiles(t, map[string]string{"config.ini": "tls-cert = foo\nfoo = bar"}, func(dir string) {
+			var o options
+			name := filepath.Join(dir, "config.ini")
+			s := Strict(Merge(
+				File(name),
+				Lenient(Env("myapp", []string{"MYAPP_TLS_CERT=bar"})),
+				Flags([]string{"--bar", "baz"}, FlagsDefault),
+			))
+
+			err := Apply(&o, s)
+			if !errors.Is(err, ErrUnexpectedKeys) {
+				t.Fatal("failed to fail with the right error", err)
+			}
+
+			if !strings.Contains(err.Error(), "foo (source="+name+")") ||
+				!strings.Contains(err.Error(), "bar (source=flags)") ||
+				strings.Contains(err.Error(), "tls (source=env)") {
+				t.Error("failed to report unexpected keys with the source", err)
+			}
+		})
+	})
+
+	t.Run("overridden source ignored", func(t *testing.T) {
+		var o options
+		s := Override(
+			Strict(iniString("foo = bar")),
+			Strict(iniString("tls-cert = foo")),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Error(err)
+		}
+	})
+
+	t.Run("help flag", func(t *testing.T) {
+		var o options
+		s := Strict(Flags([]string{"--help"}, FlagsDefault))
+		if err := Apply(&o, s); err != nil {
+			t.Error(err)
+		}
+
+		if !HelpRequested(&o, s) {
+			t.Error("failed to detect help request through strict source")
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/aryszka/config/keys"
+)
+
+// Option modifies how the config is applied to the target.
+type Option func(*options)
+
+type options struct {
+	tagNames      []string
+	allErrors     bool
+	transactional bool
+	unsetEmptyEnv bool
+}
+
+type structField struct {
+	field   reflect.StructField
+	name    string
+	aliases []string
+}
+
+// TagNames sets the struct tags, e.g. json or yaml, that the key names are taken from, in the order of
+// precedence, when a field has no config tag. Fields tagged with "-" are ignored.
+func TagNames(names ...string) Option {
+	return func(o *options) {
+		o.tagNames = append(o.tagNames, names...)
+	}
+}
+
+// AllErrors makes Apply continue through the whole target after a value failed, and return all the
+// problems found, as Errors.
+func AllErrors() Option {
+	return func(o *options) {
+		o.allErrors = true
+	}
+}
+
+// Transactional makes Apply decode into a copy of the target, and change the target only when the whole
+// config was applied and validated successfully.
+func Transactional() Option {
+	return func(o *options) {
+		o.transactional = true
+	}
+}
+
+// UnsetEmptyEnv makes the environment variables with an empty value unset the keys set by the previous
+// sources of Merge, instead of setting an empty string.
+func UnsetEmptyEnv() Option {
+	return func(o *options) {
+		o.unsetEmptyEnv = true
+	}
+}
+
+func makeOptions(o []Option) options {
+	var opts options
+	for _, oi := range o {
+		oi(&opts)
+	}
+
+	return opts
+}
+
+// the config tag has the form of `config:"name,alias=old-name"`, where the name can be empty, and the
+// alias can be repeated. The merge option is handled by fieldMergeStrategy. The other tags only provide the
+// name, and their further options are ignored.
+func parseFieldTag(f reflect.StructField, o options) (name string, aliases []string, skip bool) {
+	if tag, ok := f.Tag.Lookup("config"); ok {
+		parts := strings.Split(tag, ",")
+		if parts[0] == "-" && len(parts) == 1 {
+			return "", nil, true
+		}
+
+		name = parts[0]
+		for _, p := range parts[1:] {
+			if strings.HasPrefix(p, "alias=") {
+				aliases = append(aliases, keys.CanonicalSymbol(strings.TrimPrefix(p, "alias=")))
+			}
+		}
+	}
+
+	for _, tn := range o.tagNames {
+		if name != "" {
+			break
+		}
+
+		tag, ok := f.Tag.Lookup(tn)
+		if !ok {
+			continue
+		}
+
+		tagName := strings.Split(tag, ",")[0]
+		if tagName == "-" && tag == "-" {
+			return "", nil, true
+		}
+
+		name = tagName
+	}
+
+	if name == "" {
+		name = f.Name
+	}
+
+	return keys.CanonicalSymbol(name), aliases, false
+}
+
+// the merge strategy of a field from the config tag, e.g. `config:"plugin-dir,merge=append"`
+func fieldMergeStrategy(f reflect.StructField) (MergeStrategy, bool, error) {
+	tag, ok := f.Tag.Lookup("config")
+	if !ok {
+		return DeepMerge, false, nil
+	}
+
+	for _, p := range strings.Split(tag, ",")[1:] {
+		if !strings.HasPrefix(p, "merge=") {
+			continue
+		}
+
+		name := strings.TrimPrefix(p, "merge=")
+		s, ok := mergeStrategies[name]
+		if !ok {
+			return DeepMerge, false, invalidMergeStrategy(name)
+		}
+
+		return s, true, nil
+	}
+
+	return DeepMerge, false, nil
+}
+
+// the exported, not ignored fields of a struct type, in the order of their declaration
+func structFields(t reflect.Type, o options) []structField {
+	var fields []structField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !exported(f.Name) {
+			continue
+		}
+
+		name, aliases, skip := parseFieldTag(f, o)
+		if skip {
+			continue
+		}
+
+		fields = append(fields, structField{field: f, name: name, aliases: aliases})
+	}
+
+	return fields
+}
+
+// the fields by their names and aliases. When the names collide, the field declared first wins, and the
+// names take precedence over the aliases.
+func fieldsByKey(t reflect.Type, o options) map[string]reflect.StructField {
+	fields := structFields(t, o)
+	m := make(map[string]reflect.StructField)
+	for _, f := range fields {
+		if _, has := m[f.name]; !has {
+			m[f.name] = f.field
+		}
+	}
+
+	for _, f := range fields {
+		for _, a := range f.aliases {
+			if _, has := m[a]; !has {
+				m[a] = f.field
+			}
+		}
+	}
+
+	return m
+}
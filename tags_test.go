@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTags(t *testing.T) {
+	t.Run("name", func(t *testing.T) {
+		var o struct {
+			Foo int `config:"bar"`
+		}
+
+		if err := Apply(&o, jsonString(`{"foo": 21, "bar": 42}`)); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != 42 {
+			t.Error("failed to apply tagged name", o.Foo)
+		}
+	})
+
+	t.Run("alias", func(t *testing.T) {
+		type target struct {
+			Foo int `config:",alias=old-foo,alias=older-foo"`
+		}
+
+		t.Run("only alias", func(t *testing.T) {
+			var o target
+			if err := Apply(&o, iniString("older-foo = 42")); err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Foo != 42 {
+				t.Error("failed to apply alias", o.Foo)
+			}
+		})
+
+		t.Run("name takes precedence", func(t *testing.T) {
+			var o target
+			if err := Apply(&o, iniString("old-foo = 21\nfoo = 42")); err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Foo != 42 {
+				t.Error("failed to prefer the name over the alias", o.Foo)
+			}
+		})
+	})
+
+	t.Run("skip", func(t *testing.T) {
+		o := struct {
+			Foo int `config:"-"`
+			Bar int
+		}{Foo: 21}
+
+		if err := Apply(&o, jsonString(`{"foo": 42, "bar": 36}`)); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != 21 || o.Bar != 36 {
+			t.Error("failed to skip field", o)
+		}
+	})
+
+	t.Run("json and yaml tags", func(t *testing.T) {
+		type target struct {
+			Foo int `json:"jsonFoo,omitempty" yaml:"yaml_foo"`
+			Bar int `json:"-"`
+			Baz int `json:",omitempty"`
+			Qux int `json:"jsonQux" config:"qux"`
+		}
+
+		const doc = `{"json-foo": 42, "yaml-foo": 21, "bar": 36, "baz": 15, "qux": 12}`
+
+		t.Run("ignored without option", func(t *testing.T) {
+			var o target
+			if err := Apply(&o, jsonString(`{"foo": 42, "json-foo": 21}`)); err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Foo != 42 {
+				t.Error("failed to ignore the json tag", o.Foo)
+			}
+		})
+
+		t.Run("honoured", func(t *testing.T) {
+			var o target
+			if err := Apply(&o, jsonString(doc), TagNames("json", "yaml")); err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Foo != 42 || o.Bar != 0 || o.Baz != 15 || o.Qux != 12 {
+				t.Error("failed to honour json tags", o)
+			}
+		})
+
+		t.Run("precedence", func(t *testing.T) {
+			var o target
+			if err := Apply(&o, jsonString(doc), TagNames("yaml", "json")); err != nil {
+				t.Fatal(err)
+			}
+
+			if o.Foo != 21 {
+				t.Error("failed to honour tag precedence", o.Foo)
+			}
+		})
+	})
+
+	t.Run("nested", func(t *testing.T) {
+		var o struct {
+			Foo []struct {
+				Bar int `json:"baz"`
+			} `json:"qux"`
+		}
+
+		s := jsonString(`{"qux": [{"baz": 21}, {"baz": 42}]}`)
+		if err := Apply(&o, s, TagNames("json")); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(o.Foo) != 2 || o.Foo[0].Bar != 21 || o.Foo[1].Bar != 42 {
+			t.Error("failed to apply nested tags", o)
+		}
+	})
+
+	t.Run("flags", func(t *testing.T) {
+		var o struct {
+			Verbose bool `config:"v,alias=verbose"`
+			Port    int  `json:"listen-port"`
+		}
+
+		s := Flags([]string{"-v", "foo", "--listen-port", "8080"}, FlagsDefault)
+		p, err := ApplyWithPositional(&o, s, TagNames("json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !o.Verbose || o.Port != 8080 || len(p) != 1 || p[0] != "foo" {
+			t.Error("failed to apply tagged flags", o, p)
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		var o struct {
+			Foo int `config:"bar,alias=baz"`
+			Qux int `config:"-"`
+		}
+
+		if err := Apply(&o, Strict(iniString("baz = 42"))); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := Apply(&o, Strict(iniString("qux = 42"))); !errors.Is(err, ErrUnexpectedKeys) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("help", func(t *testing.T) {
+		var o struct {
+			Foo int `json:"fooBar"`
+			Bar int `config:"-"`
+		}
+
+		h := Help(&o, "", FlagsDefault, TagNames("json"))
+		if !strings.Contains(h, "--foo-bar int") || strings.Contains(h, "--bar") {
+			t.Error("failed to generate help from the tags", h)
+		}
+	})
+}
@@ -1,29 +1,28 @@
 package main
 
-/*
 import (
 	"fmt"
 	"log"
 
-	"github.com/aryszka/config"
+	"github.com/aryszka/config/config"
 )
 
 type TestOptions struct {
-	Foo int
-	Bar bool
-	Baz []int
+	Foo int   `help:"just foo"`
+	Bar bool  `help:"just bar"`
+	Baz []int `help:"just baz"`
 }
 
 func main() {
 	var o TestOptions
 
-	c := config.New(&o)
-	if err := c.Apply(); err != nil {
+	c := config.New()
+	if err := c.Apply(&o); err != nil {
 		log.Fatalln(c.HelpFor(err))
 	} else if c.HelpRequested() {
 		fmt.Println(c.Help())
+		return
 	}
 
 	fmt.Printf("options: %v\n", o)
 }
-*/
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+func invalidDuration(value interface{}, err error) error {
+	return fmt.Errorf("%w: invalid duration: %v: %v", ErrInvalidInputValue, value, err)
+}
+
+func invalidTime(value interface{}, err error) error {
+	return fmt.Errorf("%w: invalid time: %v: %v", ErrInvalidInputValue, value, err)
+}
+
+func invalidUnit(unit string) error {
+	return fmt.Errorf("%w: invalid duration unit: %s", ErrInvalidTarget, unit)
+}
+
+// the unit of the durations defined as plain numbers, taken from the unit struct tag, e.g. `unit:"ms"`
+func durationUnit(n Node) (time.Duration, error) {
+	unit := tagOf(n).Get("unit")
+	if unit == "" {
+		return time.Nanosecond, nil
+	}
+
+	d, err := time.ParseDuration("1" + unit)
+	if err != nil || d <= 0 {
+		return 0, invalidUnit(unit)
+	}
+
+	return d, nil
+}
+
+func setDuration(v reflect.Value, i int64, unit time.Duration) (bool, error) {
+	if i > math.MaxInt64/int64(unit) || i < math.MinInt64/int64(unit) {
+		return false, overflow(i)
+	}
+
+	v.SetInt(i * int64(unit))
+	return true, nil
+}
+
+func applyDuration(v reflect.Value, n Node) (bool, error) {
+	t := n.Type()
+
+	if t&(Int|String) == 0 {
+		return false, invalidNumericValue(typeString(t))
+	}
+
+	if t&List != 0 {
+		return zeroOrOne(applyDuration, v, n)
+	}
+
+	unit, err := durationUnit(n)
+	if err != nil {
+		return false, err
+	}
+
+	value := n.Primitive()
+	if svalue, ok := value.(string); ok {
+		if i, err := parseInt(svalue); err == nil {
+			return setDuration(v, i, unit)
+		}
+
+		d, err := time.ParseDuration(svalue)
+		if err != nil {
+			return false, invalidDuration(value, err)
+		}
+
+		v.SetInt(int64(d))
+		return true, nil
+	}
+
+	var i int64
+	if _, err := applyInt(reflect.ValueOf(&i).Elem(), n); err != nil {
+		return false, err
+	}
+
+	return setDuration(v, i, unit)
+}
+
+// the layout of the time values is taken from the layout struct tag, e.g. `layout:"2006-01-02"`, and it
+// defaults to RFC 3339
+func applyTime(v reflect.Value, n Node) (bool, error) {
+	t := n.Type()
+
+	if t == Nil {
+		v.Set(reflect.Zero(v.Type()))
+		return true, nil
+	}
+
+	if t&String == 0 {
+		return false, invalidStringValue(typeString(t))
+	}
+
+	if t&List != 0 {
+		return zeroOrOne(applyTime, v, n)
+	}
+
+	value := n.Primitive()
+	svalue, ok := value.(string)
+	if !ok {
+		return false, invalidStringValue(value)
+	}
+
+	layout := tagOf(n).Get("layout")
+	if layout == "" {
+		layout = time.RFC3339Nano
+	}
+
+	tm, err := time.Parse(layout, svalue)
+	if err != nil {
+		return false, invalidTime(value, err)
+	}
+
+	v.Set(reflect.ValueOf(tm))
+	return true, nil
+}
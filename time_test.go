@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApplyToDuration(t *testing.T) {
+	t.Run("duration string", func(t *testing.T) {
+		var o struct{ PollTimeout time.Duration }
+		if err := Apply(&o, iniString("poll-timeout = 3s")); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.PollTimeout != 3*time.Second {
+			t.Error("failed to apply duration", o.PollTimeout)
+		}
+	})
+
+	t.Run("integer as nanoseconds", func(t *testing.T) {
+		var o struct{ Foo, Bar time.Duration }
+		if err := Apply(&o, Merge(iniString("foo = 42"), jsonString(`{"bar": 36}`))); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != 42 || o.Bar != 36 {
+			t.Error("failed to apply integer duration", o)
+		}
+	})
+
+	t.Run("integer with unit", func(t *testing.T) {
+		var o struct {
+			Foo time.Duration   `unit:"ms"`
+			Bar *time.Duration  `unit:"s"`
+			Baz []time.Duration `unit:"m"`
+		}
+
+		s := Merge(
+			iniString("foo = 42\nbaz = 1\nbaz = 2m"),
+			tomlString("bar = 36"),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != 42*time.Millisecond ||
+			o.Bar == nil || *o.Bar != 36*time.Second ||
+			len(o.Baz) != 2 || o.Baz[0] != time.Minute || o.Baz[1] != 2*time.Minute {
+			t.Error("failed to apply duration with unit", o)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var o struct{ Foo time.Duration }
+		err := Apply(&o, iniString("foo = 3x"))
+		if !errors.Is(err, ErrInvalidInputValue) || !strings.Contains(err.Error(), "3x") {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("not integer", func(t *testing.T) {
+		var o struct{ Foo time.Duration }
+		err := Apply(&o, jsonString(`{"foo": 1.5}`))
+		if !errors.Is(err, ErrInvalidInputValue) || strings.Count(err.Error(), ErrInvalidInputValue.Error()) != 1 {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		var o struct {
+			Foo time.Duration `unit:"h"`
+		}
+
+		if err := Apply(&o, iniString("foo = 9223372036854775807")); !errors.Is(err, ErrNumericOverflow) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("invalid unit", func(t *testing.T) {
+		var o struct {
+			Foo time.Duration `unit:"parsec"`
+		}
+
+		if err := Apply(&o, iniString("foo = 42")); !errors.Is(err, ErrInvalidTarget) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+}
+
+func TestApplyToTime(t *testing.T) {
+	t.Run("rfc 3339", func(t *testing.T) {
+		var o struct{ Foo, Bar time.Time }
+		s := Merge(
+			jsonString(`{"foo": "2019-10-28T12:30:00Z"}`),
+			tomlString("bar = 1979-05-27T07:32:00.5+02:00"),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if !o.Foo.Equal(time.Date(2019, 10, 28, 12, 30, 0, 0, time.UTC)) {
+			t.Error("failed to apply time", o.Foo)
+		}
+
+		if !o.Bar.Equal(time.Date(1979, 5, 27, 5, 32, 0, 5e8, time.UTC)) {
+			t.Error("failed to apply time", o.Bar)
+		}
+	})
+
+	t.Run("layout", func(t *testing.T) {
+		var o struct {
+			Day  time.Time   `layout:"2006-01-02"`
+			Days []time.Time `layout:"2006-01-02"`
+		}
+
+		s := Merge(
+			tomlString("day = 1979-05-27"),
+			iniString("days = 2019-10-28\ndays = 2019-10-29"),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if !o.Day.Equal(time.Date(1979, 5, 27, 0, 0, 0, 0, time.UTC)) ||
+			len(o.Days) != 2 || o.Days[1].Day() != 29 {
+			t.Error("failed to apply time with layout", o)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		var o struct{ Foo time.Time }
+		err := Apply(&o, iniString("foo = yesterday"))
+		if !errors.Is(err, ErrInvalidInputValue) || !strings.Contains(err.Error(), "yesterday") {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+
+	t.Run("not string", func(t *testing.T) {
+		var o struct{ Foo time.Time }
+		if err := Apply(&o, jsonString(`{"foo": 42}`)); !errors.Is(err, ErrInvalidInputValue) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+}
@@ -0,0 +1,91 @@
+package config
+
+import (
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+type tomlReader struct {
+	input       io.Reader
+	typeMapping map[NodeType]NodeType
+}
+
+func newTOMLReader(r io.Reader) *tomlReader {
+	return &tomlReader{
+		input: r,
+		typeMapping: map[NodeType]NodeType{
+			Int: Number,
+		},
+	}
+}
+
+// the local date and time values are decoded with these pseudo time zones, and we keep their original format
+func formatTOMLTime(t time.Time) string {
+	switch t.Location().String() {
+	case "datetime-local":
+		return t.Format("2006-01-02T15:04:05.999999999")
+	case "date-local":
+		return t.Format("2006-01-02")
+	case "time-local":
+		return t.Format("15:04:05.999999999")
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+func sanitizeTOML(o interface{}) interface{} {
+	switch ot := o.(type) {
+	case []interface{}:
+		for i := range ot {
+			ot[i] = sanitizeTOML(ot[i])
+		}
+
+		return ot
+	case []map[string]interface{}:
+		l := make([]interface{}, len(ot))
+		for i := range ot {
+			l[i] = sanitizeTOML(ot[i])
+		}
+
+		return l
+	case map[string]interface{}:
+		for key := range ot {
+			ot[key] = sanitizeTOML(ot[key])
+		}
+
+		return ot
+	case time.Time:
+		return formatTOMLTime(ot)
+	default:
+		return o
+	}
+}
+
+func (l *tomlReader) Read() (interface{}, error) {
+	b, err := ioutil.ReadAll(l.input)
+	if err != nil {
+		return nil, err
+	}
+
+	var o map[string]interface{}
+	if err := toml.Unmarshal(b, &o); err != nil {
+		return nil, err
+	}
+
+	if len(o) == 0 {
+		return nil, ErrNoConfig
+	}
+
+	return sanitizeTOML(o), nil
+}
+
+func (l *tomlReader) rewind() bool { return rewind(l.input) }
+
+func (l tomlReader) TypeMapping() map[NodeType]NodeType {
+	return l.typeMapping
+}
+
+func TOML(r io.Reader) Source { return WithReader(newTOMLReader(r)) }
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"bytes"
+	"testing"
+)
+
+func tomlString(t string) Source {
+	return TOML(bytes.NewBufferString(t))
+}
+
+func TestTOML(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		o := struct{ Foo int }{42}
+		if err := Apply(&o, tomlString("")); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != 42 {
+			t.Error("failed to leave defaults", o.Foo)
+		}
+	})
+
+	t.Run("tables", func(t *testing.T) {
+		type server struct {
+			Host string
+			Port int
+		}
+
+		var o struct {
+			Title   string
+			Owner   struct{ Name string }
+			Servers []server
+			Limits  map[string]map[string]int
+			Created string
+			Day     string
+			Ratio   float64
+			Big     int64
+		}
+
+		s := tomlString(`
+			title = "example"
+			created = 1979-05-27T07:32:00Z
+			day = 1979-05-27
+			ratio = 1
+			big = 9007199254740993
+
+			[owner]
+			name = "Tom"
+
+			[[servers]]
+			host = "alpha"
+			port = 8001
+
+			[[servers]]
+			host = "beta"
+			port = 8002
+
+			[limits]
+			rate = { burst = 10 }
+		`)
+
+		if err := Apply(&o, Merge(s, tomlString("[limits.connections]\nmax = 42"))); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Title != "example" || o.Owner.Name != "Tom" {
+			t.Error("failed to apply table", o)
+		}
+
+		if len(o.Servers) != 2 ||
+			o.Servers[0].Host != "alpha" || o.Servers[0].Port != 8001 ||
+			o.Servers[1].Host != "beta" || o.Servers[1].Port != 8002 {
+			t.Error("failed to apply array of tables", o.Servers)
+		}
+
+		if o.Limits["rate"]["burst"] != 10 || o.Limits["connections"]["max"] != 42 {
+			t.Error("failed to merge tables", o.Limits)
+		}
+
+		if o.Created != "1979-05-27T07:32:00Z" || o.Day != "1979-05-27" {
+			t.Error("failed to apply datetime", o.Created, o.Day)
+		}
+
+		if o.Ratio != 1 {
+			t.Error("failed to apply integer to float", o.Ratio)
+		}
+
+		if o.Big != 9007199254740993 {
+			t.Error("failed to apply integer without precision loss", o.Big)
+		}
+	})
+
+	t.Run("inline table", func(t *testing.T) {
+		var o struct{ Rate struct{ Burst, Period int } }
+		if err := Apply(&o, tomlString("rate = { burst = 10, period = 30 }")); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Rate.Burst != 10 || o.Rate.Period != 30 {
+			t.Error("failed to apply inline table", o)
+		}
+	})
+
+	t.Run("merge with ini and env", func(t *testing.T) {
+		var o struct{ Foo, Bar, Baz int }
+		s := Merge(
+			tomlString("foo = 1\nbar = 2\nbaz = 3"),
+			iniString("bar = 4"),
+			Env("myapp", []string{"MYAPP_BAZ=5"}),
+		)
+
+		if err := Apply(&o, s); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Foo != 1 || o.Bar != 4 || o.Baz != 5 {
+			t.Error("failed to merge sources", o)
+		}
+	})
+}
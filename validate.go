@@ -0,0 +1,240 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrValidationFailed = errors.New("validation failed")
+
+func validationFailed(path []string, value interface{}, rule, arg string) error {
+	if arg != "" {
+		rule = fmt.Sprintf("%s=%s", rule, arg)
+	}
+
+	return &Error{Key: path, Value: value, Err: fmt.Errorf("%w: %s", ErrValidationFailed, rule)}
+}
+
+func invalidRule(path []string, rule, arg string) error {
+	return &Error{Key: path, Err: fmt.Errorf("%w: invalid validation rule: %s=%s", ErrInvalidTarget, rule, arg)}
+}
+
+func extendPath(path []string, symbol string) []string {
+	return append(path[:len(path):len(path)], symbol)
+}
+
+// numbers are compared by their value, durations can be set as duration strings, while strings, lists and
+// maps are compared by their length
+func compareLimit(v reflect.Value, limit string) (int, error) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var l int64
+		var err error
+		if v.Type() == durationType {
+			var d time.Duration
+			d, err = time.ParseDuration(limit)
+			l = int64(d)
+		} else {
+			l, err = strconv.ParseInt(limit, 10, 64)
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		return compareInt64(v.Int(), l), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		l, err := strconv.ParseUint(limit, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case v.Uint() < l:
+			return -1, nil
+		case v.Uint() > l:
+			return 1, nil
+		default:
+			return 0, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		l, err := strconv.ParseFloat(limit, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		switch {
+		case v.Float() < l:
+			return -1, nil
+		case v.Float() > l:
+			return 1, nil
+		default:
+			return 0, nil
+		}
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+		l, err := strconv.Atoi(limit)
+		if err != nil {
+			return 0, err
+		}
+
+		return compareInt64(int64(v.Len()), int64(l)), nil
+	default:
+		return 0, errors.New("unsupported type")
+	}
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func validateLimit(v reflect.Value, path []string, rule, limit string, fail int) error {
+	c, err := compareLimit(v, limit)
+	if err != nil {
+		return invalidRule(path, rule, limit)
+	}
+
+	if c == fail {
+		return validationFailed(path, v.Interface(), rule, limit)
+	}
+
+	return nil
+}
+
+func validateRules(v reflect.Value, tag reflect.StructTag, path []string) error {
+	if _, ok := tag.Lookup("required"); ok && v.IsZero() {
+		return validationFailed(path, nil, "required", "")
+	}
+
+	// the rules other than required don't apply to the missing optional values
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+
+		v = v.Elem()
+	}
+
+	if limit, ok := tag.Lookup("min"); ok {
+		if err := validateLimit(v, path, "min", limit, -1); err != nil {
+			return err
+		}
+	}
+
+	if limit, ok := tag.Lookup("max"); ok {
+		if err := validateLimit(v, path, "max", limit, 1); err != nil {
+			return err
+		}
+	}
+
+	if oneOf, ok := tag.Lookup("oneof"); ok {
+		var found bool
+		value := fmt.Sprint(v.Interface())
+		for _, option := range strings.Split(oneOf, ",") {
+			if value == option {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return validationFailed(path, v.Interface(), "oneof", oneOf)
+		}
+	}
+
+	if pattern, ok := tag.Lookup("pattern"); ok {
+		rx, err := regexp.Compile(pattern)
+		if err != nil || v.Kind() != reflect.String {
+			return invalidRule(path, "pattern", pattern)
+		}
+
+		if !rx.MatchString(v.String()) {
+			return validationFailed(path, v.Interface(), "pattern", pattern)
+		}
+	}
+
+	return nil
+}
+
+// validates the fields of the structures found in the value, at any depth, in the order of the declaration
+// of the fields, the indexes of the lists, and the sorted keys of the maps. The Validate methods are called
+// after the contained values were validated successfully.
+func validate(v reflect.Value, path []string, o options) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+
+		v = v.Elem()
+	}
+
+	t := v.Type()
+	if t == timeType || isCustomDecoded(t) {
+		return callValidate(v, path)
+	}
+
+	// without the AllErrors option, validation stops at the first error
+	var errs []error
+	collect := func(err error) bool {
+		if err == nil {
+			return true
+		}
+
+		errs = append(errs, err)
+		return o.allErrors
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		for _, f := range structFields(t, o) {
+			fv := v.FieldByIndex(f.field.Index)
+			fp := extendPath(path, f.name)
+			if !collect(validateRules(fv, f.field.Tag, fp)) {
+				return errs[0]
+			}
+
+			if !collect(validate(fv, fp, o)) {
+				return errs[0]
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !collect(validate(v.Index(i), extendPath(path, strconv.Itoa(i)), o)) {
+				return errs[0]
+			}
+		}
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil
+		}
+
+		mkeys := v.MapKeys()
+		sort.Slice(mkeys, func(i, j int) bool { return mkeys[i].String() < mkeys[j].String() })
+		for _, key := range mkeys {
+			// copied to be addressable for the Validate methods with pointer receivers
+			mv := reflect.New(t.Elem()).Elem()
+			mv.Set(v.MapIndex(key))
+			if !collect(validate(mv, extendPath(path, key.String()), o)) {
+				return errs[0]
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return joinErrors(errs)
+	}
+
+	return callValidate(v, path)
+}
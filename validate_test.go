@@ -0,0 +1,140 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// returns the key of the failing field and the failing rule in the form of key: rule
+func failingRule(err error) string {
+	var e *Error
+	if !errors.As(err, &e) {
+		return ""
+	}
+
+	return strings.Join(e.Key, ".") + ": " + strings.TrimPrefix(e.Err.Error(), ErrValidationFailed.Error()+": ")
+}
+
+func TestValidate(t *testing.T) {
+	type kubernetes struct {
+		Enabled bool
+		APIURL  string `config:"api-url" required:"true" pattern:"^https://"`
+	}
+
+	type target struct {
+		Port    int           `min:"1" max:"65535" default:"8080"`
+		Level   string        `oneof:"debug,info,warn" default:"info"`
+		Timeout time.Duration `max:"1m"`
+		Tags    []string      `max:"2"`
+		Limit   *int          `min:"1"`
+		Source  struct{ Kubernetes kubernetes }
+	}
+
+	const valid = `{"source": {"kubernetes": {"api-url": "https://kubernetes"}}}`
+
+	t.Run("valid", func(t *testing.T) {
+		var o target
+		if err := Apply(&o, jsonString(valid)); err != nil {
+			t.Fatal(err)
+		}
+
+		if o.Port != 8080 || o.Source.Kubernetes.APIURL != "https://kubernetes" {
+			t.Error("failed to apply valid config", o)
+		}
+	})
+
+	for _, test := range []struct {
+		title    string
+		source   Source
+		config   string
+		expected string
+	}{{
+		title:    "required",
+		source:   iniString("port = 80"),
+		expected: "source.kubernetes.api-url: required",
+	}, {
+		title:    "required without config",
+		source:   Env("myapp", []string{}),
+		expected: "source.kubernetes.api-url: required",
+	}, {
+		title:    "min",
+		config:   `port = 0`,
+		expected: "port: min=1",
+	}, {
+		title:    "max",
+		config:   `port = 65536`,
+		expected: "port: max=65535",
+	}, {
+		title:    "oneof",
+		config:   `level = trace`,
+		expected: "level: oneof=debug,info,warn",
+	}, {
+		title:    "pattern",
+		config:   "[source.kubernetes]\napi-url = http://kubernetes",
+		expected: "source.kubernetes.api-url: pattern=^https://",
+	}, {
+		title:    "duration",
+		config:   `timeout = 2m`,
+		expected: "timeout: max=1m",
+	}, {
+		title:    "length",
+		config:   "tags = foo\ntags = bar\ntags = baz",
+		expected: "tags: max=2",
+	}, {
+		title:    "pointer",
+		config:   `limit = 0`,
+		expected: "limit: min=1",
+	}} {
+		t.Run(test.title, func(t *testing.T) {
+			var o target
+			s := test.source
+			if s == nil {
+				s = Merge(jsonString(valid), iniString(test.config))
+			}
+
+			err := Apply(&o, s)
+			if !errors.Is(err, ErrValidationFailed) {
+				t.Fatal("failed to fail with the right error", err)
+			}
+
+			if failingRule(err) != test.expected {
+				t.Error("failed to report the failing rule", err)
+			}
+		})
+	}
+
+	t.Run("list items and map values", func(t *testing.T) {
+		type item struct {
+			Name string `required:"true"`
+		}
+
+		type target struct {
+			Items []item
+			Named map[string]item
+		}
+
+		var o target
+		err := Apply(&o, jsonString(`{"items": [{"name": "foo"}, {}]}`))
+		if !errors.Is(err, ErrValidationFailed) || failingRule(err) != "items.1.name: required" {
+			t.Error("failed to validate list items", err)
+		}
+
+		o = target{}
+		err = Apply(&o, jsonString(`{"named": {"foo": {"name": "bar"}, "baz": {}}}`))
+		if !errors.Is(err, ErrValidationFailed) || failingRule(err) != "named.baz.name: required" {
+			t.Error("failed to validate map values", err)
+		}
+	})
+
+	t.Run("invalid rule", func(t *testing.T) {
+		var o struct {
+			Foo bool `min:"1"`
+		}
+
+		if err := Apply(&o, jsonString(`{"foo": true}`)); !errors.Is(err, ErrInvalidTarget) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+}
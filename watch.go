@@ -0,0 +1,195 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// implemented by the sources that cache the result of the first read, but can read again after reset, e.g.
+// the files
+type resettableSource interface {
+	reset()
+}
+
+// implemented by the sources that read files, e.g. File
+type fileBackedSource interface {
+	files() []string
+}
+
+// Update is sent to the subscribers of a Watcher or a Reloader after the config was reloaded. Value is a
+// pointer of the same type as the watched target. When reloading failed, Value is nil, and Err holds the
+// error.
+type Update struct {
+	Value interface{}
+	Err   error
+}
+
+type fileState struct {
+	exists  bool
+	modTime time.Time
+	size    int64
+}
+
+type subscribers struct {
+	mx   sync.Mutex
+	list []func(Update)
+}
+
+// Watcher reloads the config when the files of its source change.
+type Watcher struct {
+	target      reflect.Value
+	source      Source
+	options     []Option
+	interval    time.Duration
+	files       map[string]fileState
+	subscribers subscribers
+	stopOnce    sync.Once
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+const defaultWatchInterval = time.Second
+
+func resetSource(s Source) {
+	if rs, ok := s.(resettableSource); ok {
+		rs.reset()
+	}
+
+	if cs, ok := s.(compositeSource); ok {
+		for _, si := range cs.children() {
+			resetSource(si)
+		}
+	}
+}
+
+func sourceFiles(s Source) []string {
+	var f []string
+	if fs, ok := s.(fileBackedSource); ok {
+		f = append(f, fs.files()...)
+	}
+
+	if cs, ok := s.(compositeSource); ok {
+		for _, si := range cs.children() {
+			f = append(f, sourceFiles(si)...)
+		}
+	}
+
+	return f
+}
+
+func statFiles(names []string) map[string]fileState {
+	states := make(map[string]fileState)
+	for _, name := range names {
+		fi, err := os.Stat(name)
+		if err != nil {
+			states[name] = fileState{}
+			continue
+		}
+
+		states[name] = fileState{exists: true, modTime: fi.ModTime(), size: fi.Size()}
+	}
+
+	return states
+}
+
+func (w *Watcher) changed() bool {
+	current := statFiles(sourceFiles(w.source))
+	changed := len(current) != len(w.files)
+	for name, state := range current {
+		if previous, ok := w.files[name]; !ok || previous != state {
+			changed = true
+		}
+	}
+
+	w.files = current
+	return changed
+}
+
+// applies the source to a copy of the target, re-reading the sources
+func reload(target reflect.Value, s Source, o []Option) (interface{}, error) {
+	resetSource(s)
+	v := reflect.New(target.Type().Elem())
+	deepCopy(v.Elem(), target.Elem(), make(map[pointerKey]reflect.Value))
+	if err := Apply(v.Interface(), s, o...); err != nil {
+		return nil, err
+	}
+
+	return v.Interface(), nil
+}
+
+func (s *subscribers) add(f func(Update)) {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+	s.list = append(s.list, f)
+}
+
+func (s *subscribers) notify(u Update) {
+	s.mx.Lock()
+	l := make([]func(Update), len(s.list))
+	copy(l, s.list)
+	s.mx.Unlock()
+
+	for _, f := range l {
+		f(u)
+	}
+}
+
+func (w *Watcher) run() {
+	defer close(w.done)
+	t := time.NewTicker(w.interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			if !w.changed() {
+				continue
+			}
+
+			v, err := reload(w.target, w.source, w.options)
+			w.subscribers.notify(Update{Value: v, Err: err})
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// Watch polls the files of the source, e.g. the ones created with File, and when they change, it re-reads
+// the source and applies it to a copy of the target. The subscribers receive the copy, or the error when
+// reloading failed. The target itself is not changed, it serves as the initial value of the copies, e.g.
+// holding the defaults, and it should not be changed by the caller while watching either. When interval is
+// not positive, the files are checked every second. The watcher needs to be stopped with Stop.
+func Watch(target interface{}, s Source, interval time.Duration, o ...Option) (*Watcher, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, invalidTarget(reflect.TypeOf(target))
+	}
+
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	w := &Watcher{
+		target:   v,
+		source:   s,
+		options:  o,
+		interval: interval,
+		files:    statFiles(sourceFiles(s)),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	go w.run()
+	return w, nil
+}
+
+// Subscribe registers a function to be called after every reload. It is called from the goroutine of the
+// watcher.
+func (w *Watcher) Subscribe(f func(Update)) { w.subscribers.add(f) }
+
+// Stop stops watching the files. It waits for the running notifications to finish.
+func (w *Watcher) Stop() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	<-w.done
+}
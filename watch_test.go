@@ -0,0 +1,109 @@
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	type target struct {
+		Foo int
+		Bar string
+	}
+
+	receive := func(t *testing.T, u <-chan Update) Update {
+		select {
+		case ui := <-u:
+			return ui
+		case <-time.After(3 * time.Second):
+			t.Fatal("timeout")
+			return Update{}
+		}
+	}
+
+	t.Run("reload on change", func(t *testing.T) {
+		withTestFiles(t, map[string]string{"config.json": `{"foo": 21}`}, func(dir string) {
+			name := filepath.Join(dir, "config.json")
+			initial := &target{Bar: "baz"}
+			s := Merge(File(name), Env("myapp", []string{"MYAPP_BAR=qux"}))
+
+			w, err := Watch(initial, s, 10*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer w.Stop()
+			u := make(chan Update, 3)
+			w.Subscribe(func(ui Update) { u <- ui })
+
+			// changing the size, too, in case the file system has a low resolution modification time
+			if err := ioutil.WriteFile(name, []byte(`{"foo": 420}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ui := receive(t, u)
+			if ui.Err != nil {
+				t.Fatal(ui.Err)
+			}
+
+			if o := ui.Value.(*target); o.Foo != 420 || o.Bar != "qux" {
+				t.Error("failed to reload", o)
+			}
+
+			if initial.Foo != 0 || initial.Bar != "baz" {
+				t.Error("failed to leave the initial target untouched", initial)
+			}
+
+			if err := ioutil.WriteFile(name, []byte(`{"foo": "invalid"}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			ui = receive(t, u)
+			if !errors.Is(ui.Err, ErrInvalidInputValue) || ui.Value != nil {
+				t.Error("failed to notify about the error", ui)
+			}
+
+			if err := os.Remove(name); err != nil {
+				t.Fatal(err)
+			}
+
+			ui = receive(t, u)
+			if o := ui.Value.(*target); ui.Err != nil || o.Foo != 0 || o.Bar != "qux" {
+				t.Error("failed to reload without the file", o, ui.Err)
+			}
+		})
+	})
+
+	t.Run("override", func(t *testing.T) {
+		withTestFiles(t, map[string]string{"config.ini": "foo = 21"}, func(dir string) {
+			first := filepath.Join(dir, "config.ini")
+			second := filepath.Join(dir, "config.json")
+			w, err := Watch(&target{}, Override(File(first), File(second)), 10*time.Millisecond)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			defer w.Stop()
+			u := make(chan Update, 3)
+			w.Subscribe(func(ui Update) { u <- ui })
+
+			if err := ioutil.WriteFile(second, []byte(`{"foo": 42}`), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if ui := receive(t, u); ui.Err != nil || ui.Value.(*target).Foo != 42 {
+				t.Error("failed to reload", ui.Value, ui.Err)
+			}
+		})
+	})
+
+	t.Run("invalid target", func(t *testing.T) {
+		if _, err := Watch(target{}, File("config.json"), 0); !errors.Is(err, ErrInvalidTarget) {
+			t.Error("failed to fail with the right error", err)
+		}
+	})
+}
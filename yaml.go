@@ -56,6 +56,8 @@ func sanitizeYAML(o interface{}) (interface{}, error) {
 	}
 }
 
+func (l yamlReader) rewind() bool { return rewind(l.input) }
+
 func (l yamlReader) Read() (interface{}, error) {
 	b, err := ioutil.ReadAll(l.input)
 	if err != nil {